@@ -11,11 +11,12 @@ func ForecastPortfolioHandler(w http.ResponseWriter, r *http.Request) {
 	req, err := decode[models.ForecastPortfolioRequest](r)
 	if err != nil {
 		encodeError(w, 400, err)
+		return
 	}
 	forecast, forecastErr := simulator.ForecastFuturePortfolioValueByYear(req)
 	if forecastErr != nil {
 		encodeError(w, 400, forecastErr)
 	} else {
-		encode(w, 500, forecast)
+		encode(w, 200, forecast)
 	}
 }
@@ -5,10 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/guilam34/financial_planner/models"
-	"github.com/guilam34/financial_planner/test_utils"
 )
 
 func TestForecastPortfolio(t *testing.T) {
@@ -18,13 +19,13 @@ func TestForecastPortfolio(t *testing.T) {
 			AnnualInflationRate: 0.0,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
@@ -41,7 +42,7 @@ func TestForecastPortfolio(t *testing.T) {
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
@@ -52,11 +53,11 @@ func TestForecastPortfolio(t *testing.T) {
 		expectedResponse := models.ForecastPortfolioResponse{
 			Portfolios: []models.Portfolio{
 				models.Portfolio{
-					models.Equities: 200_000,
+					models.Equities: models.Dollars(200_000),
 				},
 				models.Portfolio{
-					models.Equities: 245_000,
-					models.Cash:     5_000,
+					models.Equities: models.Dollars(245_000),
+					models.Cash:     models.Dollars(5_000),
 				},
 			},
 		}
@@ -80,7 +81,7 @@ func TestForecastPortfolio(t *testing.T) {
 
 			for assetType, expectedVal := range expectedPortfolio {
 				actualVal := actualPortfolio[assetType]
-				if !test_utils.AlmostEqual(actualVal, expectedVal) {
+				if actualVal != expectedVal {
 					t.Errorf("expected %v but got %v", expectedResponse, actualResponse)
 					t.FailNow()
 				}
@@ -97,13 +98,13 @@ func TestForecastPortfolioWithIncompleteRequest(t *testing.T) {
 			AnnualInflationRate: 0.0,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
@@ -138,3 +139,237 @@ func TestForecastPortfolioWithMalformedRequest(t *testing.T) {
 		}
 	})
 }
+
+func TestForecastPortfolioWithNamedAndLegacyAssetTypes(t *testing.T) {
+	t.Run("accepts custom asset names and legacy asset numbers", func(t *testing.T) {
+		portfolioRequestBuf := new(bytes.Buffer)
+		portfolioRequestBuf.WriteString(`{
+			"EndYear": 1,
+			"PortfolioAllocation": {
+				"0": {"ReturnRate": 0.1, "Allocation": 0.5},
+				"REIT": {"ReturnRate": 0.2, "Allocation": 0.5}
+			},
+			"InitPortfolio": {"0": 100000, "REIT": 100000}
+		}`)
+
+		request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", portfolioRequestBuf)
+		response := httptest.NewRecorder()
+
+		ForecastPortfolioHandler(response, request)
+
+		var actualResponse models.ForecastPortfolioResponse
+		json.NewDecoder(response.Body).Decode(&actualResponse)
+		if len(actualResponse.Portfolios) != 2 {
+			t.Fatalf("expected 2 portfolios but got %v", actualResponse)
+		}
+		endPortfolio := actualResponse.Portfolios[1]
+		if endPortfolio[models.Equities] != models.Dollars(110_000) || endPortfolio["REIT"] != models.Dollars(120_000) {
+			t.Errorf("expected 110000 of equities and 120000 of REITs but got %v", endPortfolio)
+		}
+	})
+}
+
+func TestForecastPortfolioWithUnknownAssetTypeNumber(t *testing.T) {
+	t.Run("rejects asset numbers without a preset", func(t *testing.T) {
+		portfolioRequestBuf := new(bytes.Buffer)
+		portfolioRequestBuf.WriteString(`{"EndYear": 1, "PortfolioAllocation": {"7": {"Allocation": 1.0}}}`)
+
+		request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", portfolioRequestBuf)
+		response := httptest.NewRecorder()
+
+		ForecastPortfolioHandler(response, request)
+
+		if response.Result().StatusCode != 400 {
+			t.Errorf("expected 400 but got %d", response.Code)
+		}
+	})
+}
+
+func TestForecastPortfolioWithNamedAndLegacyEnums(t *testing.T) {
+	t.Run("accepts enum names and legacy enum numbers", func(t *testing.T) {
+		for _, requestBody := range []string{
+			`{"EndYear": 1, "RebalancingStrategy": "every_n_years", "RebalanceCadence": 1,
+				"PortfolioAllocation": {"equities": {"ReturnRate": 0.1, "Allocation": 0.5}, "bonds": {"Allocation": 0.5}},
+				"InitPortfolio": {"equities": 100000}}`,
+			`{"EndYear": 1, "RebalancingStrategy": 1, "RebalanceCadence": 1,
+				"PortfolioAllocation": {"0": {"ReturnRate": 0.1, "Allocation": 0.5}, "1": {"Allocation": 0.5}},
+				"InitPortfolio": {"0": 100000}}`,
+		} {
+			request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", bytes.NewBufferString(requestBody))
+			response := httptest.NewRecorder()
+
+			ForecastPortfolioHandler(response, request)
+
+			var actualResponse models.ForecastPortfolioResponse
+			json.NewDecoder(response.Body).Decode(&actualResponse)
+			if len(actualResponse.Portfolios) != 2 {
+				t.Fatalf("expected 2 portfolios but got %v", actualResponse)
+			}
+			endPortfolio := actualResponse.Portfolios[1]
+			if endPortfolio[models.Equities] != models.Dollars(55_000) || endPortfolio[models.Bonds] != models.Dollars(55_000) {
+				t.Errorf("expected 55000 of equities and bonds but got %v", endPortfolio)
+			}
+		}
+	})
+}
+
+func TestForecastPortfolioWithCents(t *testing.T) {
+	t.Run("decodes amounts to the cent and encodes them as dollar numbers", func(t *testing.T) {
+		portfolioRequestBuf := new(bytes.Buffer)
+		portfolioRequestBuf.WriteString(`{"EndYear": 1,
+			"AnnualPortfolioBalanceChanges": [{"Amount": 0.1, "StartYear": 1, "EndYear": 1}],
+			"PortfolioAllocation": {"equities": {"Allocation": 1.0}},
+			"InitPortfolio": {"equities": 1000.2}}`)
+
+		request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", portfolioRequestBuf)
+		response := httptest.NewRecorder()
+
+		ForecastPortfolioHandler(response, request)
+
+		if !strings.Contains(response.Body.String(), `"Portfolios":[{"equities":1000.2},{"equities":1000.3}]`) {
+			t.Errorf("expected portfolios of 1000.2 and 1000.3 but got %v", response.Body.String())
+		}
+	})
+}
+
+func TestForecastPortfolioWithUnknownEnumName(t *testing.T) {
+	t.Run("rejects enum names that are not defined", func(t *testing.T) {
+		portfolioRequestBuf := new(bytes.Buffer)
+		portfolioRequestBuf.WriteString(`{"EndYear": 1, "RebalancingStrategy": "every_other_year"}`)
+
+		request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", portfolioRequestBuf)
+		response := httptest.NewRecorder()
+
+		ForecastPortfolioHandler(response, request)
+
+		if response.Result().StatusCode != 400 {
+			t.Errorf("expected 400 but got %d", response.Code)
+		}
+	})
+}
+
+func TestForecastPortfolioWithInvalidFields(t *testing.T) {
+	t.Run("returns every invalid field with its code", func(t *testing.T) {
+		portfolioRequestBuf := new(bytes.Buffer)
+		portfolioRequestBuf.WriteString(`{
+			"EndYear": -1,
+			"PortfolioAllocation": {"equities": {"Allocation": 1.0, "Volatility": -0.1}}
+		}`)
+
+		request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", portfolioRequestBuf)
+		response := httptest.NewRecorder()
+
+		ForecastPortfolioHandler(response, request)
+
+		if response.Result().StatusCode != 400 {
+			t.Fatalf("expected 400 but got %d", response.Code)
+		}
+		var requestError models.RequestError
+		json.NewDecoder(response.Body).Decode(&requestError)
+		expectedFieldErrors := []models.FieldError{
+			{Field: "EndYear", Code: models.OutOfRange, Message: "end year must be between 0 and 150"},
+			{Field: "PortfolioAllocation.equities.Volatility", Code: models.OutOfRange, Message: "asset volatility must be greater than or equal to 0"},
+		}
+		if !slices.Equal(requestError.FieldErrors, expectedFieldErrors) {
+			t.Errorf("expected %v but got %v", expectedFieldErrors, requestError.FieldErrors)
+		}
+	})
+}
+
+func TestForecastPortfolioWithMistypedField(t *testing.T) {
+	t.Run("points at the field that doesn't decode", func(t *testing.T) {
+		portfolioRequestBuf := new(bytes.Buffer)
+		portfolioRequestBuf.WriteString(`{"EndYear": "ten"}`)
+
+		request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", portfolioRequestBuf)
+		response := httptest.NewRecorder()
+
+		ForecastPortfolioHandler(response, request)
+
+		var requestError models.RequestError
+		json.NewDecoder(response.Body).Decode(&requestError)
+		if len(requestError.FieldErrors) != 1 || requestError.FieldErrors[0].Field != "EndYear" ||
+			requestError.FieldErrors[0].Code != models.InvalidType {
+			t.Errorf("expected an invalid type error for EndYear but got %v", requestError)
+		}
+	})
+}
+
+type UndecodableFieldTestCase struct {
+	CaseName string
+	Body     string
+	Field    string
+	Code     models.ValidationCode
+}
+
+var undecodableFieldCases = []UndecodableFieldTestCase{
+	{
+		CaseName: "UnknownEnumName",
+		Body:     `{"SimulationMode": "quantum"}`,
+		Field:    "SimulationMode",
+		Code:     models.OutOfRange,
+	},
+	{
+		CaseName: "UnknownEnumNumber",
+		Body:     `{"Accounts": [{"AccountType": 9}]}`,
+		Field:    "Accounts[0].AccountType",
+		Code:     models.OutOfRange,
+	},
+	{
+		CaseName: "EnumOfWrongKind",
+		Body:     `{"TimeStep": true}`,
+		Field:    "TimeStep",
+		Code:     models.InvalidType,
+	},
+	{
+		CaseName: "UnknownAssetNumber",
+		Body:     `{"PortfolioAllocation": {"7": {"Allocation": 1.0}}}`,
+		Field:    "PortfolioAllocation.7",
+		Code:     models.OutOfRange,
+	},
+	{
+		CaseName: "AssetNumberAlongsideItsName",
+		Body:     `{"PortfolioAllocation": {"0": {"Allocation": 0.5}, "equities": {"Allocation": 0.5}}}`,
+		Field:    "PortfolioAllocation.equities",
+		Code:     models.DuplicateName,
+	},
+	{
+		CaseName: "AssetNumberAlongsideItsNameInAccount",
+		Body:     `{"Accounts": [{"Holdings": {"1": 1, "bonds": 2}}]}`,
+		Field:    "Accounts[0].Holdings.bonds",
+		Code:     models.DuplicateName,
+	},
+	{
+		CaseName: "NonNumericAmount",
+		Body:     `{"WithdrawalFloor": "lots"}`,
+		Field:    "WithdrawalFloor",
+		Code:     models.InvalidType,
+	},
+	{
+		CaseName: "AmountTooLarge",
+		Body:     `{"AnnualPortfolioBalanceChanges": [{"Amount": 1e30}]}`,
+		Field:    "AnnualPortfolioBalanceChanges[0].Amount",
+		Code:     models.OutOfRange,
+	},
+}
+
+func TestForecastPortfolioWithUndecodableFields(t *testing.T) {
+	for _, test := range undecodableFieldCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, "/forecastPortfolio", bytes.NewBufferString(test.Body))
+			response := httptest.NewRecorder()
+
+			ForecastPortfolioHandler(response, request)
+
+			if response.Result().StatusCode != 400 {
+				t.Fatalf("expected 400 but got %d", response.Code)
+			}
+			var requestError models.RequestError
+			json.NewDecoder(response.Body).Decode(&requestError)
+			if len(requestError.FieldErrors) != 1 || requestError.FieldErrors[0].Field != test.Field ||
+				requestError.FieldErrors[0].Code != test.Code {
+				t.Errorf("expected a %v error for %v but got %v", test.Code, test.Field, requestError)
+			}
+		})
+	}
+}
@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/guilam34/financial_planner/models"
 )
@@ -17,18 +24,134 @@ func encode[T any](w http.ResponseWriter, status int, v T) error {
 	return nil
 }
 
+// Problems the models' own decoders find with a value are pointed at the field holding it. Requests
+// that decode are walked as well, since map keys that decode to the same key, such as an asset
+// type's legacy number alongside its name, would otherwise silently overwrite each other.
 func decode[T any](r *http.Request) (T, error) {
 	var v T
-	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return v, fmt.Errorf("read body: %w", err)
+	}
+	err = json.Unmarshal(data, &v)
+	var decodeErr *models.DecodeError
+	if err == nil || errors.As(err, &decodeErr) {
+		err = findUndecodableField(data, reflect.TypeFor[T](), err)
+	}
+	if err != nil {
 		return v, fmt.Errorf("decode json: %w", err)
 	}
 	return v, nil
 }
 
+// Walks the JSON alongside the type it decodes into until a value's own decoder fails or two map
+// keys decode to the same key, returning the failure as a validation error for the value's field.
+// The error is returned as it is when no value fails on its own.
+func findUndecodableField(data []byte, t reflect.Type, err error) error {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if decoder.Decode(&value) != nil {
+		return err
+	}
+	if fieldErr, ok := findFieldError(value, t, ""); ok {
+		return &models.ValidationError{FieldErrors: []models.FieldError{fieldErr}}
+	}
+	return err
+}
+
+func findFieldError(value any, t reflect.Type, path string) (models.FieldError, bool) {
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[json.Unmarshaler]()) {
+		data, _ := json.Marshal(value)
+		err := reflect.New(t).Interface().(json.Unmarshaler).UnmarshalJSON(data)
+		return getFieldError(path, err)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return findFieldError(value, t.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		elems, _ := value.([]any)
+		for i, elem := range elems {
+			if fieldErr, ok := findFieldError(elem, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); ok {
+				return fieldErr, true
+			}
+		}
+	case reflect.Map:
+		entries, _ := value.(map[string]any)
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		keysByDecodedKey := map[any]string{}
+		for _, key := range keys {
+			keyPath := joinFieldPath(path, key)
+			if reflect.PointerTo(t.Key()).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+				decodedKey := reflect.New(t.Key())
+				err := decodedKey.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key))
+				if fieldErr, ok := getFieldError(keyPath, err); ok {
+					return fieldErr, true
+				}
+				if otherKey, ok := keysByDecodedKey[decodedKey.Elem().Interface()]; ok {
+					return models.FieldError{
+						Field:   keyPath,
+						Code:    models.DuplicateName,
+						Message: fmt.Sprintf("%s and %s name the same key", otherKey, key),
+					}, true
+				}
+				keysByDecodedKey[decodedKey.Elem().Interface()] = key
+			}
+			if fieldErr, ok := findFieldError(entries[key], t.Elem(), keyPath); ok {
+				return fieldErr, true
+			}
+		}
+	case reflect.Struct:
+		// Keys match field names regardless of case, the same as when decoding
+		entries, _ := value.(map[string]any)
+		for _, field := range reflect.VisibleFields(t) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			for key, entry := range entries {
+				if !strings.EqualFold(key, field.Name) {
+					continue
+				}
+				if fieldErr, ok := findFieldError(entry, field.Type, joinFieldPath(path, field.Name)); ok {
+					return fieldErr, true
+				}
+			}
+		}
+	}
+	return models.FieldError{}, false
+}
+
+func getFieldError(path string, err error) (models.FieldError, bool) {
+	var decodeErr *models.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return models.FieldError{}, false
+	}
+	return models.FieldError{Field: path, Code: decodeErr.Code, Message: decodeErr.Message}, true
+}
+
+func joinFieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
 func encodeError(w http.ResponseWriter, status int, err error) {
 	reqErr := models.RequestError{
 		Error:   http.StatusText(status),
 		Message: err.Error(),
 	}
+	var validationErr *models.ValidationError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &validationErr) {
+		reqErr.FieldErrors = validationErr.FieldErrors
+	} else if errors.As(err, &typeErr) {
+		reqErr.FieldErrors = []models.FieldError{{Field: typeErr.Field, Code: models.InvalidType, Message: typeErr.Error()}}
+	}
 	encode(w, status, reqErr)
 }
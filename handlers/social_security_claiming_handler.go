@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/guilam34/financial_planner/models"
+	"github.com/guilam34/financial_planner/simulator"
+)
+
+func SocialSecurityClaimingHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := decode[models.SocialSecurityClaimingRequest](r)
+	if err != nil {
+		encodeError(w, 400, err)
+		return
+	}
+	claimingStrategies, optimizeErr := simulator.OptimizeSocialSecurityClaiming(req)
+	if optimizeErr != nil {
+		encodeError(w, 400, optimizeErr)
+	} else {
+		encode(w, 200, claimingStrategies)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+func TestSocialSecurityClaiming(t *testing.T) {
+	t.Run("ranks every claiming age", func(t *testing.T) {
+		claimingRequest := models.SocialSecurityClaimingRequest{
+			ForecastRequest: models.ForecastPortfolioRequest{
+				EndYear: 30,
+				PortfolioAllocation: models.PortfolioAllocation{
+					models.Cash: {Allocation: 1.0},
+				},
+				InitPortfolio: models.Portfolio{models.Cash: models.Dollars(100_000)},
+				CurrentYear:   2024,
+				SocialSecurityBenefits: []models.SocialSecurityBenefit{
+					{PrimaryInsuranceAmount: models.Dollars(2_000), BirthYear: 1962, ClaimingAge: 67},
+				},
+			},
+		}
+		claimingRequestBuf := new(bytes.Buffer)
+		json.NewEncoder(claimingRequestBuf).Encode(claimingRequest)
+
+		request, _ := http.NewRequest(http.MethodGet, "/optimizeSocialSecurityClaiming", claimingRequestBuf)
+		response := httptest.NewRecorder()
+
+		SocialSecurityClaimingHandler(response, request)
+
+		if response.Result().StatusCode != 200 {
+			t.Fatalf("expected 200 but got %d", response.Code)
+		}
+		var actualResponse models.SocialSecurityClaimingResponse
+		json.NewDecoder(response.Body).Decode(&actualResponse)
+		if len(actualResponse.ClaimingStrategies) != 9 {
+			t.Fatalf("expected 9 claiming strategies but got %v", actualResponse.ClaimingStrategies)
+		}
+	})
+}
+
+func TestSocialSecurityClaimingWithoutBenefits(t *testing.T) {
+	t.Run("rejects forecasts without benefits", func(t *testing.T) {
+		claimingRequestBuf := new(bytes.Buffer)
+		json.NewEncoder(claimingRequestBuf).Encode(models.SocialSecurityClaimingRequest{})
+
+		request, _ := http.NewRequest(http.MethodGet, "/optimizeSocialSecurityClaiming", claimingRequestBuf)
+		response := httptest.NewRecorder()
+
+		SocialSecurityClaimingHandler(response, request)
+
+		if response.Result().StatusCode != 400 {
+			t.Errorf("expected 400 but got %d", response.Code)
+		}
+	})
+}
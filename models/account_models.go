@@ -0,0 +1,60 @@
+package models
+
+type AccountTypeEnum int
+
+const (
+	// Brokerage account where growth is taxed yearly and gains are taxed on withdrawal
+	Taxable AccountTypeEnum = iota
+	// 401(k) or traditional IRA where withdrawals are taxed as ordinary income
+	TaxDeferred
+	// Roth IRA or 401(k) where qualified withdrawals are untaxed
+	TaxFree
+	// HSA where withdrawals for qualified medical expenses are untaxed
+	HealthSavings
+)
+
+type Account struct {
+	Name        string
+	AccountType AccountTypeEnum
+	Holdings    Portfolio
+	// Amount already taxed in a taxable account, withdrawals above it are taxed as capital gains
+	CostBasis Money
+}
+
+type AccountWithdrawal struct {
+	AccountName string
+	GrossAmount Money
+	// Amount left after the tax on the withdrawal
+	NetAmount Money
+	TaxPaid   Money
+}
+
+type WithdrawalOrderEnum int
+
+const (
+	TaxableFirst WithdrawalOrderEnum = iota
+	TaxDeferredFirst
+	// Draws from every account in proportion to its value
+	ProportionalWithdrawal
+	// Draws tax-deferred accounts until income reaches the top of TargetBracketRate, then tax-free
+	// accounts before the rest
+	FillTheBracket
+)
+
+type WithdrawalStrategyEnum int
+
+const (
+	// Spending comes only from AnnualPortfolioBalanceChanges
+	FixedWithdrawals WithdrawalStrategyEnum = iota
+	// Withdraws WithdrawalRate of the initial portfolio every year
+	ConstantDollar
+	// Starts at WithdrawalRate of the initial portfolio and cuts or raises spending whenever the
+	// current withdrawal rate drifts past the guardrails
+	GuytonKlinger
+	// Spends the level payment that would deplete the portfolio by EndYear at its expected return
+	VariablePercentage
+	// Withdraws WithdrawalRate of the current portfolio, kept between WithdrawalFloor and WithdrawalCeiling
+	PercentOfPortfolio
+	// Divides the current portfolio by the IRS distribution period for the owner's age
+	RmdStyle
+)
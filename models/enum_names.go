@@ -0,0 +1,301 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// Enums are sent as snake_case names, though the numbers they were sent as before are still accepted
+
+var rebalancingStrategyNames = []string{"yearly_to_zero", "every_n_years", "outside_bands", "cash_flow"}
+var simulationModeNames = []string{"deterministic", "monte_carlo", "historical", "bootstrap"}
+var timeStepNames = []string{"annual", "quarterly", "monthly"}
+var correlationPresetNames = []string{"uncorrelated", "historical"}
+var inflationModelNames = []string{"constant", "historical", "mean_reverting", "scheduled"}
+var allocationInterpolationNames = []string{"step", "linear"}
+var accountTypeNames = []string{"taxable", "tax_deferred", "tax_free", "health_savings"}
+var withdrawalOrderNames = []string{"taxable_first", "tax_deferred_first", "proportional", "fill_the_bracket"}
+var withdrawalStrategyNames = []string{
+	"fixed", "constant_dollar", "guyton_klinger", "variable_percentage", "percent_of_portfolio", "rmd_style",
+}
+var taxCalculatorNames = []string{"flat", "bracket"}
+var filingStatusNames = []string{"single", "married_filing_jointly"}
+var rothConversionTypeNames = []string{"fixed_amount", "fill_bracket"}
+var claimingRankNames = []string{"ending_wealth", "success_probability"}
+var incomeSourceTypeNames = []string{"pension", "annuity"}
+
+// Gives the text of a JSON enum value, which is a string's contents or a legacy number's digits, or
+// nil for null
+func getEnumText(data []byte) ([]byte, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return nil, err
+		}
+		return []byte(text), nil
+	}
+	if _, err := strconv.Atoi(string(data)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func marshalEnum(enumName string, names []string, value int) ([]byte, error) {
+	if value < 0 || value >= len(names) {
+		return nil, errors.New("unknown " + enumName + " " + strconv.Itoa(value))
+	}
+	return []byte(names[value]), nil
+}
+
+func unmarshalEnum(enumName string, names []string, text []byte) (int, error) {
+	if value, err := strconv.Atoi(string(text)); err == nil {
+		if value < 0 || value >= len(names) {
+			return 0, &DecodeError{Code: OutOfRange, Message: "unknown " + enumName + " number " + string(text)}
+		}
+		return value, nil
+	}
+	for value, name := range names {
+		if name == string(text) {
+			return value, nil
+		}
+	}
+	return 0, &DecodeError{Code: OutOfRange, Message: "unknown " + enumName + " \"" + string(text) + "\""}
+}
+
+func unmarshalEnumJSON(enumName string, names []string, data []byte) (int, error) {
+	text, err := getEnumText(data)
+	if err != nil {
+		return 0, &DecodeError{Code: InvalidType, Message: enumName + " must be a name or a number"}
+	} else if text == nil {
+		return 0, nil
+	}
+	return unmarshalEnum(enumName, names, text)
+}
+
+func (r RebalancingStrategyEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("rebalancing strategy", rebalancingStrategyNames, int(r))
+}
+
+func (r *RebalancingStrategyEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("rebalancing strategy", rebalancingStrategyNames, text)
+	*r = RebalancingStrategyEnum(value)
+	return err
+}
+
+func (r *RebalancingStrategyEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("rebalancing strategy", rebalancingStrategyNames, data)
+	*r = RebalancingStrategyEnum(value)
+	return err
+}
+
+func (s SimulationModeEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("simulation mode", simulationModeNames, int(s))
+}
+
+func (s *SimulationModeEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("simulation mode", simulationModeNames, text)
+	*s = SimulationModeEnum(value)
+	return err
+}
+
+func (s *SimulationModeEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("simulation mode", simulationModeNames, data)
+	*s = SimulationModeEnum(value)
+	return err
+}
+
+func (t TimeStepEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("time step", timeStepNames, int(t))
+}
+
+func (t *TimeStepEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("time step", timeStepNames, text)
+	*t = TimeStepEnum(value)
+	return err
+}
+
+func (t *TimeStepEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("time step", timeStepNames, data)
+	*t = TimeStepEnum(value)
+	return err
+}
+
+func (c CorrelationPresetEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("correlation preset", correlationPresetNames, int(c))
+}
+
+func (c *CorrelationPresetEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("correlation preset", correlationPresetNames, text)
+	*c = CorrelationPresetEnum(value)
+	return err
+}
+
+func (c *CorrelationPresetEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("correlation preset", correlationPresetNames, data)
+	*c = CorrelationPresetEnum(value)
+	return err
+}
+
+func (i InflationModelEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("inflation model", inflationModelNames, int(i))
+}
+
+func (i *InflationModelEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("inflation model", inflationModelNames, text)
+	*i = InflationModelEnum(value)
+	return err
+}
+
+func (i *InflationModelEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("inflation model", inflationModelNames, data)
+	*i = InflationModelEnum(value)
+	return err
+}
+
+func (a AllocationInterpolationEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("allocation interpolation", allocationInterpolationNames, int(a))
+}
+
+func (a *AllocationInterpolationEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("allocation interpolation", allocationInterpolationNames, text)
+	*a = AllocationInterpolationEnum(value)
+	return err
+}
+
+func (a *AllocationInterpolationEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("allocation interpolation", allocationInterpolationNames, data)
+	*a = AllocationInterpolationEnum(value)
+	return err
+}
+
+func (a AccountTypeEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("account type", accountTypeNames, int(a))
+}
+
+func (a *AccountTypeEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("account type", accountTypeNames, text)
+	*a = AccountTypeEnum(value)
+	return err
+}
+
+func (a *AccountTypeEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("account type", accountTypeNames, data)
+	*a = AccountTypeEnum(value)
+	return err
+}
+
+func (w WithdrawalOrderEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("withdrawal order", withdrawalOrderNames, int(w))
+}
+
+func (w *WithdrawalOrderEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("withdrawal order", withdrawalOrderNames, text)
+	*w = WithdrawalOrderEnum(value)
+	return err
+}
+
+func (w *WithdrawalOrderEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("withdrawal order", withdrawalOrderNames, data)
+	*w = WithdrawalOrderEnum(value)
+	return err
+}
+
+func (w WithdrawalStrategyEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("withdrawal strategy", withdrawalStrategyNames, int(w))
+}
+
+func (w *WithdrawalStrategyEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("withdrawal strategy", withdrawalStrategyNames, text)
+	*w = WithdrawalStrategyEnum(value)
+	return err
+}
+
+func (w *WithdrawalStrategyEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("withdrawal strategy", withdrawalStrategyNames, data)
+	*w = WithdrawalStrategyEnum(value)
+	return err
+}
+
+func (t TaxCalculatorEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("tax calculator", taxCalculatorNames, int(t))
+}
+
+func (t *TaxCalculatorEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("tax calculator", taxCalculatorNames, text)
+	*t = TaxCalculatorEnum(value)
+	return err
+}
+
+func (t *TaxCalculatorEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("tax calculator", taxCalculatorNames, data)
+	*t = TaxCalculatorEnum(value)
+	return err
+}
+
+func (f FilingStatusEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("filing status", filingStatusNames, int(f))
+}
+
+func (f *FilingStatusEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("filing status", filingStatusNames, text)
+	*f = FilingStatusEnum(value)
+	return err
+}
+
+func (f *FilingStatusEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("filing status", filingStatusNames, data)
+	*f = FilingStatusEnum(value)
+	return err
+}
+
+func (r RothConversionTypeEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("roth conversion type", rothConversionTypeNames, int(r))
+}
+
+func (r *RothConversionTypeEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("roth conversion type", rothConversionTypeNames, text)
+	*r = RothConversionTypeEnum(value)
+	return err
+}
+
+func (r *RothConversionTypeEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("roth conversion type", rothConversionTypeNames, data)
+	*r = RothConversionTypeEnum(value)
+	return err
+}
+
+func (c ClaimingRankEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("claiming rank", claimingRankNames, int(c))
+}
+
+func (c *ClaimingRankEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("claiming rank", claimingRankNames, text)
+	*c = ClaimingRankEnum(value)
+	return err
+}
+
+func (c *ClaimingRankEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("claiming rank", claimingRankNames, data)
+	*c = ClaimingRankEnum(value)
+	return err
+}
+
+func (i IncomeSourceTypeEnum) MarshalText() ([]byte, error) {
+	return marshalEnum("income source type", incomeSourceTypeNames, int(i))
+}
+
+func (i *IncomeSourceTypeEnum) UnmarshalText(text []byte) error {
+	value, err := unmarshalEnum("income source type", incomeSourceTypeNames, text)
+	*i = IncomeSourceTypeEnum(value)
+	return err
+}
+
+func (i *IncomeSourceTypeEnum) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalEnumJSON("income source type", incomeSourceTypeNames, data)
+	*i = IncomeSourceTypeEnum(value)
+	return err
+}
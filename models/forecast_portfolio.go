@@ -4,12 +4,151 @@ type ForecastPortfolioRequest struct {
 	InitPortfolio                 Portfolio
 	AnnualPortfolioBalanceChanges []AnnualPortfolioBalanceChange
 	PortfolioAllocation           PortfolioAllocation
-	AnnualInflationRate           float64
-	EndYear                       int
-	RebalanceCadence              int
-	RebalancingStrategy           RebalancingStrategyEnum
+	// Rescales allocations that don't sum up to 1, such as percentages given as 70/20/10, instead of
+	// rejecting them
+	NormalizeAllocations bool
+	// Glide path of target weights by year, starting from PortfolioAllocation's weights in year 0 and
+	// holding the last waypoint's weights after it
+	AllocationSchedule      []AllocationWaypoint
+	AllocationInterpolation AllocationInterpolationEnum
+	AnnualInflationRate     float64
+	EndYear                 int
+	TimeStep                TimeStepEnum
+	RebalanceCadence        int
+	RebalancingStrategy     RebalancingStrategyEnum
+	// Drift from an asset's target weight, as a fraction of the portfolio such as 0.05 for five
+	// percentage points, that triggers band or cash-flow rebalancing
+	AbsoluteRebalanceBand float64
+	// Drift as a fraction of the target weight that triggers band or cash-flow rebalancing. The
+	// tighter band applies when both are set, as in the 5/25 rule.
+	RelativeRebalanceBand float64
+	// Cost of each rebalancing trade in basis points of the amount traded
+	TransactionCostBps float64
+	// Fixed cost of each rebalancing trade in today's dollars
+	TradeFee                  Money
+	SimulationMode            SimulationModeEnum
+	NumSimulations            int
+	Seed                      uint64
+	AnnualInflationVolatility float64
+	CorrelationPreset         CorrelationPresetEnum
+	// Overrides the preset correlation for each listed pair of assets
+	AssetCorrelations []AssetCorrelation
+	// Overrides the preset correlation between inflation and each listed asset
+	InflationCorrelations map[AssetType]float64
+	BootstrapBlockLength  int
+	InflationModel        InflationModelEnum
+	// Fraction of last year's deviation from the mean inflation rate that persists into the next year
+	InflationMeanReversion       float64
+	InitialInflationRate         float64
+	HistoricalInflationStartYear int
+	// Inflation rate for each year starting with year 1
+	InflationSchedule []float64
+	// Fraction of the household's assets paid to its advisor each year
+	AdvisoryFeeRate float64
+	// Marginal advisory fee schedule by assets under management, used in place of AdvisoryFeeRate
+	AdvisoryFeeTiers []AdvisoryFeeTier
+	// Household accounts, used in place of InitPortfolio when set
+	Accounts      []Account
+	TaxRates      TaxRates
+	TaxCalculator TaxCalculatorEnum
+	FilingStatus  FilingStatusEnum
+	// Year of the tax tables to apply, defaulting to the latest available. Brackets are assumed to
+	// keep pace with inflation since forecasts are in today's dollars.
+	TaxYear int
+	// Two letter code of the state whose income tax applies, if any
+	State           string
+	WithdrawalOrder WithdrawalOrderEnum
+	// Highest federal ordinary income rate the fill the bracket order draws tax-deferred accounts up to
+	TargetBracketRate float64
+	// Spending rule applied on top of AnnualPortfolioBalanceChanges
+	WithdrawalStrategy WithdrawalStrategyEnum
+	WithdrawalRate     float64
+	// Bounds on percent of portfolio spending in today's dollars, no ceiling applies when it's 0
+	WithdrawalFloor   Money
+	WithdrawalCeiling Money
+	// Fraction the Guyton-Klinger withdrawal rate can drift from its initial rate before spending is
+	// adjusted, defaulting to 0.2
+	GuardrailThreshold float64
+	// Fraction Guyton-Klinger spending is cut or raised by, defaulting to 0.1
+	GuardrailAdjustment float64
+	// Calendar year of the initial portfolio, needed for anything based on age
+	CurrentYear int
+	// Birth year of the tax-deferred accounts' owner, RMDs are skipped when unset
+	OwnerBirthYear  int
+	RothConversions []RothConversion
+	// Benefits of up to two spouses, who are each eligible for spousal and survivor benefits based on
+	// the other's record
+	SocialSecurityBenefits []SocialSecurityBenefit
+	// Pensions and annuities
+	IncomeSources []IncomeSource
 }
 
 type ForecastPortfolioResponse struct {
+	// Household holdings summed across accounts
 	Portfolios []Portfolio
+	// Whether NormalizeAllocations rescaled the portfolio allocation or the allocation schedule
+	AllocationsNormalized bool
+	// Holdings of each account for each year of Portfolios
+	AccountBalances [][]Account
+	// Taxes paid for each year of Portfolios
+	TaxesPaid []Money
+	// Accounts that funded the household's withdrawals for each year of Portfolios
+	AccountWithdrawals [][]AccountWithdrawal
+	// Total RMDs taken from tax-deferred accounts for each year of Portfolios
+	RequiredMinimumDistributions []Money
+	// Household spending for each year of Portfolios, from the withdrawal strategy and the balance
+	// changes without an account
+	Spending []Money
+	// Years in which any account of the deterministic forecast was rebalanced
+	RebalanceYears []int
+	// Rebalancing trades for each year of Portfolios
+	Trades [][]Trade
+	// Value bought by rebalancing as a fraction of the portfolio for each year of Portfolios
+	Turnover []float64
+	// Cost of the rebalancing trades for each year of Portfolios
+	TransactionCosts      []Money
+	TotalTransactionCosts Money
+	// Expense ratios and advisory fees paid for each year of Portfolios
+	FeesPaid      []Money
+	TotalFeesPaid Money
+	// Total Social Security benefits received for each year of Portfolios
+	SocialSecurityIncome []Money
+	// Total pension and annuity payments for each year of Portfolios, kept apart from the portfolio's
+	// withdrawals
+	GuaranteedIncome []Money
+	// Payments from each pension and annuity for each year of Portfolios
+	IncomeSourcePayments [][]IncomeSourcePayment
+	// Total converted to tax-free accounts for each year of Portfolios
+	RothConversions []Money
+	// Only set when the request has Roth conversions
+	RothConversionComparison RothConversionComparison
+	// Realized inflation for each year of Portfolios, which is 0 for the initial year
+	InflationRates  []float64
+	PercentileBands []PercentileBand
+	Depleted        bool
+	// First year withdrawals could not be fully funded, only meaningful when Depleted is set
+	DepletionYear int
+	// Sum of withdrawals that could not be funded once the portfolio was depleted
+	TotalShortfall Money
+	// Fraction of simulated paths that were never depleted
+	SuccessProbability float64
+	HistoricalPeriods  []HistoricalPeriod
+}
+
+// Distribution of the total real portfolio value across simulated paths for a single year
+type PercentileBand struct {
+	Year         int
+	Percentile5  Money
+	Percentile25 Money
+	Percentile50 Money
+	Percentile75 Money
+	Percentile95 Money
+}
+
+// Outcome of replaying the forecast over the historical returns beginning in StartYear
+type HistoricalPeriod struct {
+	StartYear         int
+	EndPortfolio      Portfolio
+	EndPortfolioValue Money
+	Survived          bool
 }
@@ -0,0 +1,42 @@
+package models
+
+type IncomeSourceTypeEnum int
+
+const (
+	// Defined-benefit pension
+	Pension IncomeSourceTypeEnum = iota
+	// Annuity bought with a premium from the portfolio, immediate when payments start at the purchase
+	// age and deferred when they start later
+	Annuity
+)
+
+// Guaranteed income paid outside of the portfolio. Payments are taxed as ordinary income, except for
+// the part of an annuity's payments that returns a premium paid with after-tax money.
+type IncomeSource struct {
+	Name             string
+	IncomeSourceType IncomeSourceTypeEnum
+	// Yearly payment in today's dollars, or its current value when payments have already started
+	AnnualAmount Money
+	// Birth year of the recipient
+	BirthYear int
+	StartAge  int
+	// Payments without a COLA are fixed in nominal terms so they lose value to inflation once they start
+	HasCola bool
+	// Age at which the recipient dies and the survivor's share starts, 0 when they outlive the forecast
+	DeathAge int
+	// Fraction of the payment that continues to the survivor
+	SurvivorFraction float64
+	// Annuities only, the premium is withdrawn from the portfolio in the year of the purchase age
+	Premium     Money
+	PurchaseAge int
+	// Account the premium is paid from, or the household's withdrawal order when unset. A premium
+	// paid from a tax-deferred account buys a qualified annuity and moves to the insurer untaxed, so
+	// its payments are taxed in full. Payments bought from a tax-free or health savings account are
+	// untaxed, and otherwise only the part beyond the premium's return is taxed.
+	PremiumAccountName string
+}
+
+type IncomeSourcePayment struct {
+	IncomeSourceName string
+	Amount           Money
+}
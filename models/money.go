@@ -0,0 +1,71 @@
+package models
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Amount of money in whole cents, so amounts add up exactly and a household's accounts always sum to
+// its portfolio to the cent. Amounts computed from a rate, such as growth, taxes and fees, are rounded
+// to the nearest cent with halves rounded away from zero before they're posted or reported. Amounts
+// are sent as JSON numbers of dollars.
+type Money int64
+
+// Rounds the dollar amount to the nearest cent
+func Dollars(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+func (m Money) Dollars() float64 {
+	return float64(m) / 100
+}
+
+// Amount times the rate, rounded to the nearest cent
+func (m Money) MulRate(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+func (m Money) String() string {
+	sign := ""
+	cents := uint64(m)
+	if m < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	text := sign + strconv.FormatUint(cents/100, 10)
+	if cents%100 == 0 {
+		return text
+	} else if cents%10 == 0 {
+		return text + "." + strconv.FormatUint(cents%100/10, 10)
+	} else if cents%100 < 10 {
+		return text + ".0" + strconv.FormatUint(cents%100, 10)
+	}
+	return text + "." + strconv.FormatUint(cents%100, 10)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Parses the number's decimal digits exactly rather than through a float, so amounts given to the
+// cent keep every cent
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	amount, ok := new(big.Rat).SetString(string(data))
+	if !ok {
+		return &DecodeError{Code: InvalidType, Message: "amount must be a number"}
+	}
+	cents := amount.Mul(amount, big.NewRat(100, 1))
+	quotient, remainder := new(big.Int).QuoRem(cents.Num(), cents.Denom(), new(big.Int))
+	if remainder.Abs(remainder).Lsh(remainder, 1).Cmp(cents.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(cents.Sign())))
+	}
+	if !quotient.IsInt64() {
+		return &DecodeError{Code: OutOfRange, Message: "amount is too large"}
+	}
+	*m = Money(quotient.Int64())
+	return nil
+}
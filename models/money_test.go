@@ -0,0 +1,90 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+type MoneyUnmarshalTestCase struct {
+	CaseName     string
+	Data         string
+	Money        Money
+	ErrorMessage string
+}
+
+var moneyUnmarshalCases = []MoneyUnmarshalTestCase{
+	{CaseName: "WholeDollars", Data: "1200", Money: 120_000},
+	{CaseName: "Cents", Data: "0.07", Money: 7},
+	{CaseName: "HalfCentAFloatWouldRoundDown", Data: "1.005", Money: 101},
+	{CaseName: "HalfCentRoundsAwayFromZero", Data: "0.125", Money: 13},
+	{CaseName: "NegativeHalfCentRoundsAwayFromZero", Data: "-0.125", Money: -13},
+	{CaseName: "LessThanHalfCentRoundsDown", Data: "0.1249999", Money: 12},
+	{CaseName: "Exponent", Data: "1.5e3", Money: 150_000},
+	{CaseName: "Null", Data: "null", Money: 0},
+	{CaseName: "LargestAmount", Data: "92233720368547758.07", Money: math.MaxInt64},
+	{CaseName: "SmallestAmount", Data: "-92233720368547758.08", Money: math.MinInt64},
+	{CaseName: "TooLarge", Data: "92233720368547758.08", ErrorMessage: "amount is too large"},
+	{CaseName: "RoundsUpToTooLarge", Data: "92233720368547758.075", ErrorMessage: "amount is too large"},
+	{CaseName: "TooSmall", Data: "-92233720368547758.09", ErrorMessage: "amount is too large"},
+	{CaseName: "String", Data: `"12"`, ErrorMessage: "amount must be a number"},
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	for _, test := range moneyUnmarshalCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			var money Money
+			err := money.UnmarshalJSON([]byte(test.Data))
+			if test.ErrorMessage != "" {
+				if err == nil || err.Error() != test.ErrorMessage {
+					t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if money != test.Money {
+				t.Errorf("expected %d cents but got %d", test.Money, money)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	expectedText := map[Money]string{
+		0:             "0",
+		5:             "0.05",
+		50:            "0.5",
+		123_456:       "1234.56",
+		100:           "1",
+		-5:            "-0.05",
+		-50:           "-0.5",
+		-123_450:      "-1234.5",
+		math.MinInt64: "-92233720368547758.08",
+		math.MaxInt64: "92233720368547758.07",
+	}
+	for money, expected := range expectedText {
+		if actual := money.String(); actual != expected {
+			t.Errorf("expected %v for %d cents but got %v", expected, int64(money), actual)
+		}
+		var decoded Money
+		if err := decoded.UnmarshalJSON([]byte(expected)); err != nil || decoded != money {
+			t.Errorf("expected %v to decode back to %d cents but got %d and %v", expected, int64(money), decoded, err)
+		}
+	}
+}
+
+func TestMoneyRounding(t *testing.T) {
+	if actual := Dollars(0.125); actual != 13 {
+		t.Errorf("expected 13 cents but got %d", actual)
+	}
+	if actual := Dollars(-0.125); actual != -13 {
+		t.Errorf("expected -13 cents but got %d", actual)
+	}
+	if actual := Money(1).MulRate(0.5); actual != 1 {
+		t.Errorf("expected half a cent to round up to 1 but got %d", actual)
+	}
+	if actual := Money(-1).MulRate(0.5); actual != -1 {
+		t.Errorf("expected negative half a cent to round down to -1 but got %d", actual)
+	}
+}
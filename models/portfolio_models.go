@@ -1,32 +1,181 @@
 package models
 
+import (
+	"slices"
+	"strconv"
+)
+
 type AnnualPortfolioBalanceChange struct {
-	Amount          float64
+	// In today's dollars, so the nominal amount follows realized inflation
+	Amount          Money
 	StartYear       int
 	EndYear         int
 	AnnualPctChange float64
+	// Account contributions go into or withdrawals come out of, defaulting to the first account for
+	// contributions and the household's withdrawal order for withdrawals
+	AccountName string
 }
 
-type AssetType int
+// Name of an asset class. Any name can be used as long as its allocation gives its return
+// assumptions, and historical data is only available for the presets. All-digit names are reserved
+// for the presets' legacy numbers.
+type AssetType string
 
 const (
-	Equities AssetType = iota
-	Bonds
-	Cash
+	Equities AssetType = "equities"
+	Bonds    AssetType = "bonds"
+	Cash     AssetType = "cash"
 )
 
+// Preset asset types in the order of the numbers they had before asset types were named
+var PresetAssetTypes = []AssetType{Equities, Bonds, Cash}
+
+// Accepts the presets' legacy numbers so requests from before asset types were named still decode.
+// Names are matched exactly, so keys that only differ by case are separate asset types rather than
+// one silently overwriting the other.
+func (a *AssetType) UnmarshalText(text []byte) error {
+	if presetIdx, err := strconv.Atoi(string(text)); err == nil {
+		if presetIdx < 0 || presetIdx >= len(PresetAssetTypes) {
+			return &DecodeError{Code: OutOfRange, Message: "unknown asset type number " + string(text)}
+		}
+		*a = PresetAssetTypes[presetIdx]
+		return nil
+	}
+	if len(text) == 0 {
+		return &DecodeError{Code: MissingValue, Message: "asset type must not be empty"}
+	}
+	*a = AssetType(text)
+	return nil
+}
+
+// Lets asset type values, not just map keys, be given as legacy JSON numbers
+func (a *AssetType) UnmarshalJSON(data []byte) error {
+	text, err := getEnumText(data)
+	if err != nil {
+		return &DecodeError{Code: InvalidType, Message: "asset type must be a name or a number"}
+	} else if text == nil {
+		return nil
+	}
+	return a.UnmarshalText(text)
+}
+
+// Orders the presets first in their legacy order followed by the other asset types by name, which
+// keeps seeded simulations of preset portfolios drawing in the same order as before
+func CompareAssetTypes(a AssetType, b AssetType) int {
+	aPresetIdx, bPresetIdx := slices.Index(PresetAssetTypes, a), slices.Index(PresetAssetTypes, b)
+	if aPresetIdx >= 0 && bPresetIdx >= 0 {
+		return aPresetIdx - bPresetIdx
+	} else if aPresetIdx >= 0 {
+		return -1
+	} else if bPresetIdx >= 0 {
+		return 1
+	}
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
 type AssetAllocation struct {
 	ReturnRate float64
 	Allocation float64
+	// Standard deviation of the annual return, only used by stochastic simulations
+	Volatility float64
+	// Fraction of the asset's value paid to its funds each year
+	ExpenseRatio float64
 }
 
 type PortfolioAllocation map[AssetType]AssetAllocation
 
-type Portfolio map[AssetType]float64
+type Portfolio map[AssetType]Money
 
 type RebalancingStrategyEnum int
 
 const (
 	YearlyToZero RebalancingStrategyEnum = iota
 	EveryNYearsByAlloc
+	// Rebalances whenever an asset's weight drifts outside its tolerance band
+	OutsideBandsByAlloc
+	// Directs contributions to underweight assets and takes withdrawals from overweight ones, only
+	// trading when the drift left after cash flows is outside the bands
+	CashFlowByAlloc
 )
+
+type SimulationModeEnum int
+
+const (
+	Deterministic SimulationModeEnum = iota
+	MonteCarlo
+	Historical
+	// Resamples blocks of consecutive historical years
+	Bootstrap
+)
+
+// Length of the periods each simulated year is split into. Shorter periods compound the annual
+// rates over the year and spread its recurring cash flows into an installment at the middle of each
+// period, while results are still reported by year.
+type TimeStepEnum int
+
+const (
+	// Cash flows land once a year after its growth
+	AnnualTimeStep TimeStepEnum = iota
+	QuarterlyTimeStep
+	MonthlyTimeStep
+)
+
+type CorrelationPresetEnum int
+
+const (
+	Uncorrelated CorrelationPresetEnum = iota
+	// Correlations measured over the bundled historical returns
+	HistoricalCorrelation
+)
+
+type AssetCorrelation struct {
+	AssetType      AssetType
+	OtherAssetType AssetType
+	Correlation    float64
+}
+
+type InflationModelEnum int
+
+const (
+	ConstantInflation InflationModelEnum = iota
+	// Replays historical inflation starting from HistoricalInflationStartYear
+	HistoricalInflation
+	// AR(1) process reverting from InitialInflationRate towards AnnualInflationRate
+	MeanRevertingInflation
+	// Year by year rates given in InflationSchedule
+	ScheduledInflation
+)
+
+type AllocationInterpolationEnum int
+
+const (
+	// Each waypoint's allocation holds until the next waypoint
+	StepAllocation AllocationInterpolationEnum = iota
+	// Allocations shift linearly between waypoints
+	LinearAllocation
+)
+
+// Target weight of each asset from Year on, assets left out are weighted 0
+type AllocationWaypoint struct {
+	Year        int
+	Allocations map[AssetType]float64
+}
+
+// Purchase, or sale when Amount is negative, of an asset made by rebalancing
+type Trade struct {
+	AccountName string
+	AssetType   AssetType
+	Amount      Money
+	Cost        Money
+}
+
+// Advisory fee rate charged on the assets under management above Threshold
+type AdvisoryFeeTier struct {
+	Threshold Money
+	Rate      float64
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+)
+
+type PortfolioUnmarshalTestCase struct {
+	CaseName  string
+	Data      string
+	Portfolio Portfolio
+}
+
+var portfolioUnmarshalCases = []PortfolioUnmarshalTestCase{
+	{
+		CaseName:  "LegacyNumbersArePresets",
+		Data:      `{"0": 1, "2": 2}`,
+		Portfolio: Portfolio{Equities: 100, Cash: 200},
+	},
+	{
+		CaseName:  "NamesOnlyDifferingByCaseStaySeparate",
+		Data:      `{"Equities": 1, "equities": 2}`,
+		Portfolio: Portfolio{"Equities": 100, Equities: 200},
+	},
+}
+
+func TestPortfolioUnmarshalJSON(t *testing.T) {
+	for _, test := range portfolioUnmarshalCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			var portfolio Portfolio
+			if err := json.Unmarshal([]byte(test.Data), &portfolio); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !maps.Equal(portfolio, test.Portfolio) {
+				t.Errorf("expected %v but got %v", test.Portfolio, portfolio)
+			}
+		})
+	}
+}
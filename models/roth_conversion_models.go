@@ -0,0 +1,34 @@
+package models
+
+type RothConversionTypeEnum int
+
+const (
+	// Converts Amount each year
+	FixedAmountConversion RothConversionTypeEnum = iota
+	// Converts until ordinary income reaches the top of TargetBracketRate
+	FillBracketConversion
+)
+
+// Yearly conversion from a tax-deferred account to a tax-free one, with the tax owed paid from
+// taxable accounts
+type RothConversion struct {
+	ConversionType RothConversionTypeEnum
+	// Amount converted each year in today's dollars, used by fixed amount conversions
+	Amount            Money
+	TargetBracketRate float64
+	StartYear         int
+	EndYear           int
+	// Defaults to the first tax-deferred account
+	FromAccountName string
+	// Defaults to the first tax-free account
+	ToAccountName string
+}
+
+// Outcome of the forecast's Roth conversions against the same forecast without them
+type RothConversionComparison struct {
+	LifetimeTaxes         Money
+	BaselineLifetimeTaxes Money
+	// Value of the final accounts after the tax owed if they were all liquidated in the final year
+	AfterTaxWealth         Money
+	BaselineAfterTaxWealth Money
+}
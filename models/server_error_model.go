@@ -3,4 +3,6 @@ package models
 type RequestError struct {
 	Error   string
 	Message string
+	// Problems with the request's fields, empty when the error isn't about particular fields
+	FieldErrors []FieldError
 }
@@ -0,0 +1,40 @@
+package models
+
+// Social Security retirement benefit of one member of the household. Benefits are in today's
+// dollars, so COLAs matching realized inflation leave them unchanged in real terms.
+type SocialSecurityBenefit struct {
+	// Monthly benefit at full retirement age
+	PrimaryInsuranceAmount Money
+	BirthYear              int
+	// Age from 62 to 70 at which benefits start
+	ClaimingAge int
+	// Age at which the benefit ends and any survivor benefit starts, 0 when they outlive the forecast
+	DeathAge int
+}
+
+type ClaimingRankEnum int
+
+const (
+	RankByEndingWealth ClaimingRankEnum = iota
+	RankBySuccessProbability
+)
+
+// Forecast to repeat for every combination of claiming ages of its Social Security benefits. Monte
+// Carlo and bootstrap forecasts split 100000 simulations between the combinations.
+type SocialSecurityClaimingRequest struct {
+	ForecastRequest ForecastPortfolioRequest
+	RankBy          ClaimingRankEnum
+}
+
+type SocialSecurityClaimingResponse struct {
+	// Every combination of claiming ages, best first
+	ClaimingStrategies []ClaimingStrategy
+}
+
+type ClaimingStrategy struct {
+	// Claiming age of each benefit in the order of the request's benefits
+	ClaimingAges []int
+	// Real value of the deterministic forecast's final portfolio
+	EndingWealth       Money
+	SuccessProbability float64
+}
@@ -0,0 +1,24 @@
+package models
+
+type TaxCalculatorEnum int
+
+const (
+	// Taxes income at the single rates given in TaxRates
+	FlatTax TaxCalculatorEnum = iota
+	// Taxes income with the progressive federal and state brackets of the embedded tax tables
+	BracketTax
+)
+
+type FilingStatusEnum int
+
+const (
+	Single FilingStatusEnum = iota
+	MarriedFilingJointly
+)
+
+type TaxRates struct {
+	OrdinaryIncomeRate float64
+	CapitalGainsRate   float64
+	// Fraction of a taxable account's positive yearly growth lost to tax on dividends and interest
+	TaxableGrowthRate float64
+}
@@ -0,0 +1,58 @@
+package models
+
+import "strings"
+
+// Machine-readable kind of problem with a request field
+type ValidationCode string
+
+const (
+	OutOfRange ValidationCode = "out_of_range"
+	// A value must come before or after another one, as with start and end years
+	InvalidOrder ValidationCode = "invalid_order"
+	// Allocations don't sum up to 1
+	InvalidSum       ValidationCode = "invalid_sum"
+	UnknownAssetType ValidationCode = "unknown_asset_type"
+	UnknownAccount   ValidationCode = "unknown_account"
+	DuplicateName    ValidationCode = "duplicate_name"
+	// The field can't be set along with another one
+	ConflictingFields ValidationCode = "conflicting_fields"
+	MissingValue      ValidationCode = "missing_value"
+	// No tax table or historical data covers the value
+	UnavailableData ValidationCode = "unavailable_data"
+	// The correlations can't all hold at once
+	InvalidCorrelations ValidationCode = "invalid_correlations"
+	// The value doesn't decode into the field's type
+	InvalidType ValidationCode = "invalid_type"
+)
+
+type FieldError struct {
+	// JSON path of the field at fault, such as AnnualPortfolioBalanceChanges[1].EndYear or
+	// PortfolioAllocation.equities.Allocation
+	Field   string
+	Code    ValidationCode
+	Message string
+}
+
+// Every problem found with a request
+type ValidationError struct {
+	FieldErrors []FieldError
+}
+
+// Problem with a single value found while decoding a request. The value's own decoder doesn't know
+// which field holds it, so whoever decodes the request points it at the field.
+type DecodeError struct {
+	Code    ValidationCode
+	Message string
+}
+
+func (d *DecodeError) Error() string {
+	return d.Message
+}
+
+func (v *ValidationError) Error() string {
+	messages := make([]string, len(v.FieldErrors))
+	for i, fieldError := range v.FieldErrors {
+		messages[i] = fieldError.Message
+	}
+	return strings.Join(messages, "; ")
+}
@@ -10,4 +10,7 @@ func AddRoutes(mux *http.ServeMux) {
 	mux.HandleFunc(
 		"/forecastPortfolio", handlers.ForecastPortfolioHandler,
 	)
+	mux.HandleFunc(
+		"/optimizeSocialSecurityClaiming", handlers.SocialSecurityClaimingHandler,
+	)
 }
@@ -0,0 +1,219 @@
+package simulator
+
+import (
+	"math"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Account proceeds beyond the year's spending are reinvested into when the household has no taxable
+// account of its own
+const reinvestmentAccountName = "Reinvestment"
+
+// Requests without accounts hold their initial portfolio in a single unnamed taxable account
+func getInitAccounts(forecastRequest models.ForecastPortfolioRequest) []models.Account {
+	if len(forecastRequest.Accounts) > 0 {
+		return forecastRequest.Accounts
+	}
+	initPortfolioValue, _, _ := getNetPortfolioValue(forecastRequest.InitPortfolio)
+	return []models.Account{
+		{
+			AccountType: models.Taxable,
+			Holdings:    forecastRequest.InitPortfolio,
+			CostBasis:   initPortfolioValue,
+		},
+	}
+}
+
+func copyAccount(account models.Account) models.Account {
+	copiedAccount := account
+	copiedAccount.Holdings = models.Portfolio{}
+	for assetType, assetVal := range account.Holdings {
+		copiedAccount.Holdings[assetType] = assetVal
+	}
+	return copiedAccount
+}
+
+// Sums each asset's holdings across every account
+func getHouseholdPortfolio(accounts []models.Account) models.Portfolio {
+	householdPortfolio := models.Portfolio{}
+	for _, account := range accounts {
+		for assetType, assetVal := range account.Holdings {
+			householdPortfolio[assetType] = householdPortfolio[assetType] + assetVal
+		}
+	}
+	return householdPortfolio
+}
+
+// Applies a year of returns to the account, returning the tax owed on a taxable account's growth.
+// Taxed growth that stays in the account adds to its cost basis so it isn't taxed again on withdrawal,
+// while untaxed appreciation stays out of the basis and is taxed as a capital gain when withdrawn.
+func growAccount(
+	account *models.Account,
+	portfolioAllocationWithRealRates models.PortfolioAllocation,
+	taxRates models.TaxRates) models.Money {
+
+	growth := models.Money(0)
+	for assetType, assetVal := range account.Holdings {
+		assetGrowth := assetVal.MulRate(portfolioAllocationWithRealRates[assetType].ReturnRate)
+		account.Holdings[assetType] = assetVal + assetGrowth
+		growth = growth + assetGrowth
+	}
+
+	if account.AccountType != models.Taxable || growth <= 0 || taxRates.TaxableGrowthRate <= 0 {
+		return 0
+	}
+	taxOwed := growth.MulRate(taxRates.TaxableGrowthRate)
+	spreadAmountByAllocation(account, -taxOwed, portfolioAllocationWithRealRates)
+	account.CostBasis = account.CostBasis + growth - taxOwed
+	return taxOwed
+}
+
+func contributeToAccount(
+	account *models.Account,
+	amount models.Money,
+	portfolioAllocation models.PortfolioAllocation) {
+
+	spreadAmountByAllocation(account, amount, portfolioAllocation)
+	account.CostBasis = account.CostBasis + amount
+}
+
+func spreadAmountByAllocation(
+	account *models.Account,
+	amount models.Money,
+	portfolioAllocation models.PortfolioAllocation) {
+
+	assetTypes := sortedAssetTypes(portfolioAllocation)
+	weights := make([]float64, len(assetTypes))
+	for i, assetType := range assetTypes {
+		weights[i] = portfolioAllocation[assetType].Allocation
+	}
+	for i, share := range splitAmount(amount, weights) {
+		account.Holdings[assetTypes[i]] = account.Holdings[assetTypes[i]] + share
+	}
+}
+
+// Splits the amount in proportion to the weights with each share rounded to the cent. The cents
+// rounding gains or loses go to the largest share, so the shares add up to exactly the part of the
+// amount the weights cover.
+func splitAmount(amount models.Money, weights []float64) []models.Money {
+	shares := make([]models.Money, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+	exactTotal, roundedTotal := 0.0, models.Money(0)
+	largestIdx := 0
+	for i, weight := range weights {
+		exactTotal = exactTotal + float64(amount)*weight
+		shares[i] = amount.MulRate(weight)
+		roundedTotal = roundedTotal + shares[i]
+		if math.Abs(weight) > math.Abs(weights[largestIdx]) {
+			largestIdx = i
+		}
+	}
+	shares[largestIdx] = shares[largestIdx] + models.Money(math.Round(exactTotal)) - roundedTotal
+	return shares
+}
+
+// Fractions of a gross withdrawal from the account that are taxed as ordinary income and as
+// capital gains
+func getWithdrawalIncomeFractions(account models.Account, accountValue models.Money) (ordinaryFraction float64, gainsFraction float64) {
+	switch account.AccountType {
+	case models.Taxable:
+		return 0.0, max(0.0, 1.0-float64(account.CostBasis)/float64(accountValue))
+	case models.TaxDeferred:
+		return 1.0, 0.0
+	default:
+		return 0.0, 0.0
+	}
+}
+
+// Moves RMD proceeds or income that weren't needed for spending into a taxable account
+func reinvestProceeds(
+	accounts []models.Account,
+	amount models.Money,
+	portfolioAllocation models.PortfolioAllocation) []models.Account {
+
+	if amount <= 0 {
+		return accounts
+	}
+	for i := range accounts {
+		if accounts[i].AccountType == models.Taxable {
+			contributeToAccount(&accounts[i], amount, portfolioAllocation)
+			return accounts
+		}
+	}
+	reinvestmentAccount := models.Account{
+		Name:        reinvestmentAccountName,
+		AccountType: models.Taxable,
+		Holdings:    models.Portfolio{},
+	}
+	contributeToAccount(&reinvestmentAccount, amount, portfolioAllocation)
+	return append(accounts, reinvestmentAccount)
+}
+
+// Stands in for an amount without a limit, such as a withdrawal capped only by the account's value
+const unlimitedAmount = models.Money(math.MaxInt64)
+
+// Withdraws enough from the account, grossed up for taxes, to fund as much of the net amount as the
+// account can cover without withdrawing more than the max gross amount
+func withdrawFromAccount(
+	account *models.Account,
+	netAmount models.Money,
+	maxGrossAmount models.Money,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) models.AccountWithdrawal {
+
+	withdrawal := models.AccountWithdrawal{AccountName: account.Name}
+	accountValue, _, _ := getNetPortfolioValue(account.Holdings)
+	availableAmount := min(accountValue, maxGrossAmount)
+	if availableAmount <= 0 || netAmount <= 0 {
+		return withdrawal
+	}
+
+	ordinaryFraction, gainsFraction := getWithdrawalIncomeFractions(*account, accountValue)
+	getNetOfTax := func(grossAmount models.Money) models.Money {
+		return grossAmount - income.getMarginalTax(grossAmount.MulRate(ordinaryFraction), grossAmount.MulRate(gainsFraction))
+	}
+
+	withdrawal.GrossAmount = availableAmount
+	withdrawal.NetAmount = getNetOfTax(availableAmount)
+	if withdrawal.NetAmount > netAmount {
+		withdrawal.GrossAmount = solveGrossAmount(getNetOfTax, netAmount, availableAmount)
+		withdrawal.NetAmount = netAmount
+	}
+	if withdrawal.GrossAmount >= accountValue {
+		account.Holdings = emptyPortfolio(account.Holdings)
+	} else {
+		spreadAmountByAllocation(account, -withdrawal.GrossAmount, portfolioAllocation)
+	}
+	account.CostBasis = account.CostBasis.MulRate(1 - float64(withdrawal.GrossAmount)/float64(accountValue))
+	income.add(withdrawal.GrossAmount.MulRate(ordinaryFraction), withdrawal.GrossAmount.MulRate(gainsFraction))
+	withdrawal.TaxPaid = withdrawal.GrossAmount - withdrawal.NetAmount
+	return withdrawal
+}
+
+// Bisects for the smallest gross withdrawal, in cents, that leaves the net amount after tax.
+// Marginal rates are below 100% so the amount left after tax grows with the gross withdrawal.
+func solveGrossAmount(getNetOfTax func(models.Money) models.Money, netAmount models.Money, maxGrossAmount models.Money) models.Money {
+	lowerBound, upperBound := netAmount, maxGrossAmount
+	if getNetOfTax(lowerBound) >= netAmount {
+		return lowerBound
+	}
+	for upperBound-lowerBound > 1 {
+		midpoint := lowerBound + (upperBound-lowerBound)/2
+		if getNetOfTax(midpoint) < netAmount {
+			lowerBound = midpoint
+		} else {
+			upperBound = midpoint
+		}
+	}
+	return upperBound
+}
+
+func findAccount(accounts []models.Account, accountName string) int {
+	return slices.IndexFunc(accounts, func(account models.Account) bool {
+		return account.Name == accountName
+	})
+}
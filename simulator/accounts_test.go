@@ -0,0 +1,324 @@
+package simulator
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+var singleAssetAllocation = models.PortfolioAllocation{
+	models.Equities: {Allocation: 1.0},
+}
+
+var accountTaxRates = models.TaxRates{
+	OrdinaryIncomeRate: 0.25,
+	CapitalGainsRate:   0.2,
+	TaxableGrowthRate:  0.1,
+}
+
+func newFlatTaxableIncome() *taxableIncome {
+	return &taxableIncome{taxCalculator: FlatTaxCalculator{taxRates: accountTaxRates}}
+}
+
+type WithdrawFromAccountTestCase struct {
+	CaseName     string
+	Account      models.Account
+	NetAmount    float64
+	NetWithdrawn float64
+	TaxPaid      float64
+	EndValue     float64
+	EndCostBasis float64
+}
+
+var withdrawFromAccountCases = []WithdrawFromAccountTestCase{
+	{
+		CaseName: "TaxDeferredIsGrossedUpAtOrdinaryIncomeRate",
+		Account: models.Account{
+			AccountType: models.TaxDeferred,
+			Holdings:    models.Portfolio{models.Equities: models.Dollars(100_000)},
+		},
+		NetAmount:    15_000,
+		NetWithdrawn: 15_000,
+		TaxPaid:      5_000,
+		EndValue:     80_000,
+	},
+	{
+		CaseName: "TaxableIsTaxedOnGainsOnly",
+		Account: models.Account{
+			AccountType: models.Taxable,
+			Holdings:    models.Portfolio{models.Equities: models.Dollars(100_000)},
+			CostBasis:   models.Dollars(50_000),
+		},
+		NetAmount:    9_000,
+		NetWithdrawn: 9_000,
+		TaxPaid:      1_000,
+		EndValue:     90_000,
+		EndCostBasis: 45_000,
+	},
+	{
+		CaseName: "TaxFreeIsUntaxed",
+		Account: models.Account{
+			AccountType: models.TaxFree,
+			Holdings:    models.Portfolio{models.Equities: models.Dollars(100_000)},
+		},
+		NetAmount:    10_000,
+		NetWithdrawn: 10_000,
+		TaxPaid:      0,
+		EndValue:     90_000,
+	},
+	{
+		CaseName: "WithdrawalLargerThanAccount",
+		Account: models.Account{
+			AccountType: models.TaxDeferred,
+			Holdings:    models.Portfolio{models.Equities: models.Dollars(10_000)},
+		},
+		NetAmount:    15_000,
+		NetWithdrawn: 7_500,
+		TaxPaid:      2_500,
+		EndValue:     0,
+	},
+}
+
+func TestWithdrawFromAccount(t *testing.T) {
+	for _, test := range withdrawFromAccountCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			account := copyAccount(test.Account)
+			withdrawal := withdrawFromAccount(&account, models.Dollars(test.NetAmount), unlimitedAmount, singleAssetAllocation, newFlatTaxableIncome())
+			netWithdrawn, taxPaid := withdrawal.NetAmount, withdrawal.TaxPaid
+			endValue, _, _ := getNetPortfolioValue(account.Holdings)
+			if netWithdrawn != models.Dollars(test.NetWithdrawn) ||
+				taxPaid != models.Dollars(test.TaxPaid) ||
+				endValue != models.Dollars(test.EndValue) ||
+				account.CostBasis != models.Dollars(test.EndCostBasis) {
+				t.Errorf("expected %v net, %v tax, %v left with %v basis but got %v net, %v tax, %v left with %v basis",
+					test.NetWithdrawn, test.TaxPaid, test.EndValue, test.EndCostBasis,
+					netWithdrawn, taxPaid, endValue, account.CostBasis)
+			}
+		})
+	}
+}
+
+func TestWithdrawFromAccountIsLimitedByMaxGrossAmount(t *testing.T) {
+	account := models.Account{AccountType: models.TaxDeferred, Holdings: models.Portfolio{models.Equities: models.Dollars(100_000)}}
+	withdrawal := withdrawFromAccount(&account, models.Dollars(30_000), models.Dollars(20_000), singleAssetAllocation, newFlatTaxableIncome())
+	if withdrawal.GrossAmount != models.Dollars(20_000) || withdrawal.NetAmount != models.Dollars(15_000) {
+		t.Errorf("expected 20000 gross and 15000 net but got %v", withdrawal)
+	}
+	if account.Holdings[models.Equities] != models.Dollars(80_000) {
+		t.Errorf("expected 80000 left but got %v", account.Holdings[models.Equities])
+	}
+}
+
+func TestGrowAccountTaxesTaxableGrowth(t *testing.T) {
+	portfolioAllocation := models.PortfolioAllocation{
+		models.Equities: {ReturnRate: 0.1, Allocation: 1.0},
+	}
+	taxable := models.Account{
+		AccountType: models.Taxable,
+		Holdings:    models.Portfolio{models.Equities: models.Dollars(100_000)},
+		CostBasis:   models.Dollars(80_000),
+	}
+	taxDeferred := models.Account{AccountType: models.TaxDeferred, Holdings: models.Portfolio{models.Equities: models.Dollars(100_000)}}
+
+	if taxOwed := growAccount(&taxable, portfolioAllocation, accountTaxRates); taxOwed != models.Dollars(1_000) {
+		t.Errorf("expected 1000 tax on taxable growth but got %v", taxOwed)
+	}
+	if taxable.Holdings[models.Equities] != models.Dollars(109_000) {
+		t.Errorf("expected 109000 after tax but got %v", taxable.Holdings[models.Equities])
+	}
+	if taxable.CostBasis != models.Dollars(89_000) {
+		t.Errorf("expected a cost basis of 89000 but got %v", taxable.CostBasis)
+	}
+	if taxOwed := growAccount(&taxDeferred, portfolioAllocation, accountTaxRates); taxOwed != 0.0 {
+		t.Errorf("expected no tax on tax deferred growth but got %v", taxOwed)
+	}
+	if taxDeferred.Holdings[models.Equities] != models.Dollars(110_000) {
+		t.Errorf("expected 110000 but got %v", taxDeferred.Holdings[models.Equities])
+	}
+}
+
+func TestGrowAccountKeepsUntaxedGrowthOutOfCostBasis(t *testing.T) {
+	portfolioAllocation := models.PortfolioAllocation{
+		models.Equities: {ReturnRate: 0.1, Allocation: 1.0},
+	}
+	taxRates := models.TaxRates{OrdinaryIncomeRate: 0.25, CapitalGainsRate: 0.2}
+	taxable := models.Account{
+		AccountType: models.Taxable,
+		Holdings:    models.Portfolio{models.Equities: models.Dollars(100_000)},
+		CostBasis:   models.Dollars(100_000),
+	}
+
+	if taxOwed := growAccount(&taxable, portfolioAllocation, taxRates); taxOwed != 0 {
+		t.Errorf("expected no tax on growth without a taxable growth rate but got %v", taxOwed)
+	}
+	if taxable.CostBasis != models.Dollars(100_000) {
+		t.Errorf("expected the cost basis to stay at 100000 but got %v", taxable.CostBasis)
+	}
+
+	income := &taxableIncome{taxCalculator: FlatTaxCalculator{taxRates: taxRates}}
+	withdrawal := withdrawFromAccount(&taxable, models.Dollars(110_000), models.Dollars(110_000), portfolioAllocation, income)
+	if withdrawal.GrossAmount != models.Dollars(110_000) {
+		t.Errorf("expected to withdraw the whole 110000 but got %v", withdrawal.GrossAmount)
+	}
+	if withdrawal.TaxPaid != models.Dollars(2_000) {
+		t.Errorf("expected 2000 capital gains tax on the 10000 of growth but got %v", withdrawal.TaxPaid)
+	}
+}
+
+func TestForecastWithAccounts(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 2,
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(5_000), StartYear: 1, EndYear: 2, AccountName: "Roth"},
+			{Amount: models.Dollars(-30_000), StartYear: 1, EndYear: 2},
+		},
+		PortfolioAllocation: singleAssetAllocation,
+		Accounts: []models.Account{
+			{Name: "401k", AccountType: models.TaxDeferred, Holdings: models.Portfolio{models.Equities: models.Dollars(100_000)}},
+			{Name: "Roth", AccountType: models.TaxFree, Holdings: models.Portfolio{models.Equities: models.Dollars(20_000)}},
+		},
+		TaxRates: models.TaxRates{OrdinaryIncomeRate: 0.2},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	endAccounts := response.AccountBalances[2]
+	if endAccounts[0].Holdings[models.Equities] != models.Dollars(25_000) ||
+		endAccounts[1].Holdings[models.Equities] != models.Dollars(30_000) {
+		t.Errorf("expected 25000 in 401k and 30000 in Roth but got %v", endAccounts)
+	}
+	if response.Portfolios[2][models.Equities] != models.Dollars(55_000) {
+		t.Errorf("expected household total of 55000 but got %v", response.Portfolios[2])
+	}
+	if response.TaxesPaid[1] != models.Dollars(7_500) || response.TaxesPaid[2] != models.Dollars(7_500) {
+		t.Errorf("expected 7500 of tax each year but got %v", response.TaxesPaid)
+	}
+}
+
+type SplitAmountTestCase struct {
+	CaseName string
+	Amount   models.Money
+	Weights  []float64
+	Shares   []models.Money
+}
+
+var splitAmountCases = []SplitAmountTestCase{
+	{
+		CaseName: "EvenSplit",
+		Amount:   models.Dollars(100),
+		Weights:  []float64{0.25, 0.5, 0.25},
+		Shares:   []models.Money{models.Dollars(25), models.Dollars(50), models.Dollars(25)},
+	},
+	{
+		CaseName: "ThirdsAddUpToTheAmount",
+		Amount:   models.Dollars(100),
+		Weights:  []float64{1.0 / 3, 1.0 / 3, 1.0 / 3},
+		Shares:   []models.Money{models.Dollars(33.34), models.Dollars(33.33), models.Dollars(33.33)},
+	},
+	{
+		CaseName: "NegativeAmount",
+		Amount:   models.Dollars(-0.05),
+		Weights:  []float64{0.5, 0.5},
+		Shares:   []models.Money{models.Dollars(-0.02), models.Dollars(-0.03)},
+	},
+	{
+		CaseName: "PartialWeights",
+		Amount:   models.Dollars(10),
+		Weights:  []float64{0.5},
+		Shares:   []models.Money{models.Dollars(5)},
+	},
+}
+
+func TestSplitAmount(t *testing.T) {
+	for _, test := range splitAmountCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			if actual := splitAmount(test.Amount, test.Weights); !slices.Equal(actual, test.Shares) {
+				t.Errorf("expected %v but got %v", test.Shares, actual)
+			}
+		})
+	}
+}
+
+func TestForecastKeepsEveryCent(t *testing.T) {
+	thirds := models.PortfolioAllocation{
+		models.Equities: {Allocation: 1.0 / 3},
+		models.Bonds:    {Allocation: 1.0 / 3},
+		models.Cash:     {Allocation: 1.0 / 3},
+	}
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 3,
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(33.33), StartYear: 1, EndYear: 3},
+		},
+		PortfolioAllocation: thirds,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000.01)},
+		RebalancingStrategy: models.EveryNYearsByAlloc,
+		RebalanceCadence:    1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	endPortfolio := response.Portfolios[3]
+	if endValue, _, _ := getNetPortfolioValue(endPortfolio); endValue != models.Dollars(100_100) {
+		t.Errorf("expected exactly 100100 but got %v", endValue)
+	}
+	if endPortfolio[models.Equities]-endPortfolio[models.Cash] > 1 || endPortfolio[models.Bonds] != endPortfolio[models.Cash] {
+		t.Errorf("expected thirds within a cent of each other but got %v", endPortfolio)
+	}
+}
+
+var accountErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "AccountsAndInitPortfolio",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(1)},
+			Accounts:            []models.Account{{Name: "Roth", AccountType: models.TaxFree}},
+		},
+		ErrorMessage: "initial portfolio must be empty when accounts are given",
+	},
+	{
+		CaseName: "DuplicateAccountNames",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			Accounts: []models.Account{
+				{Name: "Roth", AccountType: models.TaxFree},
+				{Name: "Roth", AccountType: models.TaxDeferred},
+			},
+		},
+		ErrorMessage: "account names must be unique",
+	},
+	{
+		CaseName: "UnknownBalanceChangeAccount",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             1,
+			PortfolioAllocation: singleAssetAllocation,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(1_000), StartYear: 1, EndYear: 1, AccountName: "HSA"},
+			},
+			Accounts: []models.Account{{Name: "Roth", AccountType: models.TaxFree}},
+		},
+		ErrorMessage: "annual balance change account must be one of the accounts",
+	},
+	{
+		CaseName: "TaxRateOutOfRange",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			TaxRates:            models.TaxRates{OrdinaryIncomeRate: 1.0},
+		},
+		ErrorMessage: "tax rates must be greater than or equal to 0 and less than 1",
+	},
+}
+
+func TestForecastWithAccountsErrorCases(t *testing.T) {
+	for _, test := range accountErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
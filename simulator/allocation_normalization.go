@@ -0,0 +1,71 @@
+package simulator
+
+import (
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Summing weights such as 0.7, 0.2 and 0.1 doesn't give exactly 1 in floating point, so sums this
+// close to 1 are accepted as they are
+const allocationSumTolerance = 1e-9
+
+func isWholeAllocation(allocatedPct float64) bool {
+	return math.Abs(allocatedPct-1.0) <= allocationSumTolerance
+}
+
+// Gives the factor that brings the weights to sum up to 1, or false when they already do or when a
+// negative weight or an empty allocation leaves nothing sensible to rescale
+func getNormalizationScale(weights []float64) (float64, bool) {
+	allocatedPct := 0.0
+	for _, weight := range weights {
+		if weight < 0 {
+			return 0.0, false
+		}
+		allocatedPct = allocatedPct + weight
+	}
+	if allocatedPct == 0 || isWholeAllocation(allocatedPct) {
+		return 0.0, false
+	}
+	return 1.0 / allocatedPct, true
+}
+
+// Rescales the portfolio allocation and each allocation schedule waypoint to sum up to 1 when the
+// request asks for it, returning whether any of them was rescaled. Allocations that can't be rescaled
+// are left for validation to reject.
+func normalizeAllocations(forecastRequest models.ForecastPortfolioRequest) (models.ForecastPortfolioRequest, bool) {
+	if !forecastRequest.NormalizeAllocations {
+		return forecastRequest, false
+	}
+	normalized := false
+
+	weights := make([]float64, 0, len(forecastRequest.PortfolioAllocation))
+	for _, allocation := range forecastRequest.PortfolioAllocation {
+		weights = append(weights, allocation.Allocation)
+	}
+	if scale, ok := getNormalizationScale(weights); ok {
+		portfolioAllocation := make(models.PortfolioAllocation, len(forecastRequest.PortfolioAllocation))
+		for assetType, allocation := range forecastRequest.PortfolioAllocation {
+			allocation.Allocation = allocation.Allocation * scale
+			portfolioAllocation[assetType] = allocation
+		}
+		forecastRequest.PortfolioAllocation = portfolioAllocation
+		normalized = true
+	}
+
+	allocationSchedule := slices.Clone(forecastRequest.AllocationSchedule)
+	for i, waypoint := range allocationSchedule {
+		if scale, ok := getNormalizationScale(slices.Collect(maps.Values(waypoint.Allocations))); ok {
+			allocations := make(map[models.AssetType]float64, len(waypoint.Allocations))
+			for assetType, weight := range waypoint.Allocations {
+				allocations[assetType] = weight * scale
+			}
+			allocationSchedule[i].Allocations = allocations
+			normalized = true
+		}
+	}
+	forecastRequest.AllocationSchedule = allocationSchedule
+	return forecastRequest, normalized
+}
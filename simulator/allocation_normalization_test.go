@@ -0,0 +1,104 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type AllocationNormalizationTestCase struct {
+	CaseName              string
+	ForecastRequest       models.ForecastPortfolioRequest
+	EndPortfolio          models.Portfolio
+	AllocationsNormalized bool
+}
+
+var allocationNormalizationCases = []AllocationNormalizationTestCase{
+	{
+		CaseName: "FloatingPointSumIsAccepted",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear: 1,
+			PortfolioAllocation: models.PortfolioAllocation{
+				models.Equities: {Allocation: 0.7},
+				models.Bonds:    {Allocation: 0.2},
+				models.Cash:     {Allocation: 0.1},
+			},
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+			RebalanceCadence:    1,
+			RebalancingStrategy: models.EveryNYearsByAlloc,
+		},
+		EndPortfolio:          models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(20_000), models.Cash: models.Dollars(10_000)},
+		AllocationsNormalized: false,
+	},
+	{
+		CaseName: "PercentagesAreRescaled",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear: 1,
+			PortfolioAllocation: models.PortfolioAllocation{
+				models.Equities: {Allocation: 70},
+				models.Bonds:    {Allocation: 20},
+				models.Cash:     {Allocation: 10},
+			},
+			NormalizeAllocations: true,
+			InitPortfolio:        models.Portfolio{models.Equities: models.Dollars(100_000)},
+			RebalanceCadence:     1,
+			RebalancingStrategy:  models.EveryNYearsByAlloc,
+		},
+		EndPortfolio:          models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(20_000), models.Cash: models.Dollars(10_000)},
+		AllocationsNormalized: true,
+	},
+	{
+		CaseName: "ScheduleWaypointIsRescaled",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear: 1,
+			PortfolioAllocation: models.PortfolioAllocation{
+				models.Equities: {Allocation: 0.5},
+				models.Bonds:    {Allocation: 0.5},
+			},
+			AllocationSchedule: []models.AllocationWaypoint{
+				{Year: 1, Allocations: map[models.AssetType]float64{models.Equities: 0.3, models.Bonds: 0.699}},
+			},
+			NormalizeAllocations: true,
+			InitPortfolio:        models.Portfolio{models.Equities: models.Dollars(100_000)},
+			RebalanceCadence:     1,
+			RebalancingStrategy:  models.EveryNYearsByAlloc,
+		},
+		EndPortfolio:          models.Portfolio{models.Equities: models.Dollars(30_030.03), models.Bonds: models.Dollars(69_969.97)},
+		AllocationsNormalized: true,
+	},
+}
+
+func TestAllocationNormalizationCases(t *testing.T) {
+	for _, test := range allocationNormalizationCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			response, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if response.AllocationsNormalized != test.AllocationsNormalized {
+				t.Errorf("expected normalization to be %v but got %v", test.AllocationsNormalized, response.AllocationsNormalized)
+			}
+			endPortfolio := response.Portfolios[len(response.Portfolios)-1]
+			for assetType, expectedVal := range test.EndPortfolio {
+				if endPortfolio[assetType] != expectedVal {
+					t.Errorf("expected %v but got %v", test.EndPortfolio, endPortfolio)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizationLeavesNegativeAllocationsToValidation(t *testing.T) {
+	_, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {Allocation: 90},
+			models.Bonds:    {Allocation: -10},
+		},
+		NormalizeAllocations: true,
+	})
+	expectedMessage := "asset allocation must be between 0 and 1; asset allocation must be between 0 and 1; " +
+		"portfolio allocation percent must sum up to 1"
+	if err == nil || err.Error() != expectedMessage {
+		t.Errorf("expected %v but got %v", expectedMessage, err)
+	}
+}
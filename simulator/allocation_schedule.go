@@ -0,0 +1,49 @@
+package simulator
+
+import (
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Target weights of the glide path in effect for the year
+func getScheduledWeights(forecastRequest models.ForecastPortfolioRequest, year int) map[models.AssetType]float64 {
+	prevWaypoint := models.AllocationWaypoint{Year: 0, Allocations: map[models.AssetType]float64{}}
+	for assetType, allocation := range forecastRequest.PortfolioAllocation {
+		prevWaypoint.Allocations[assetType] = allocation.Allocation
+	}
+
+	for _, waypoint := range forecastRequest.AllocationSchedule {
+		if waypoint.Year <= year {
+			prevWaypoint = waypoint
+			continue
+		}
+		if forecastRequest.AllocationInterpolation != models.LinearAllocation {
+			break
+		}
+		progress := float64(year-prevWaypoint.Year) / float64(waypoint.Year-prevWaypoint.Year)
+		weights := map[models.AssetType]float64{}
+		for assetType := range forecastRequest.PortfolioAllocation {
+			weights[assetType] = prevWaypoint.Allocations[assetType] +
+				progress*(waypoint.Allocations[assetType]-prevWaypoint.Allocations[assetType])
+		}
+		return weights
+	}
+	return prevWaypoint.Allocations
+}
+
+// Replaces the allocation's weights with the glide path's weights for the year
+func applyAllocationSchedule(
+	portfolioAllocation models.PortfolioAllocation,
+	forecastRequest models.ForecastPortfolioRequest,
+	year int) models.PortfolioAllocation {
+
+	if len(forecastRequest.AllocationSchedule) == 0 {
+		return portfolioAllocation
+	}
+	weights := getScheduledWeights(forecastRequest, year)
+	scheduledAllocation := models.PortfolioAllocation{}
+	for assetType, allocation := range portfolioAllocation {
+		allocation.Allocation = weights[assetType]
+		scheduledAllocation[assetType] = allocation
+	}
+	return scheduledAllocation
+}
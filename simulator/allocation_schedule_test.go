@@ -0,0 +1,135 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+	"github.com/guilam34/financial_planner/test_utils"
+)
+
+type AllocationScheduleTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	Year            int
+	EquitiesWeight  float64
+}
+
+var glidePathAllocation = models.PortfolioAllocation{
+	models.Equities: {Allocation: 0.9},
+	models.Bonds:    {Allocation: 0.1},
+}
+
+var glidePathSchedule = []models.AllocationWaypoint{
+	{Year: 5, Allocations: map[models.AssetType]float64{models.Equities: 0.4, models.Bonds: 0.6}},
+	{Year: 10, Allocations: map[models.AssetType]float64{models.Equities: 0.2, models.Bonds: 0.8}},
+}
+
+var stepGlidePathRequest = models.ForecastPortfolioRequest{
+	PortfolioAllocation: glidePathAllocation,
+	AllocationSchedule:  glidePathSchedule,
+}
+
+var linearGlidePathRequest = models.ForecastPortfolioRequest{
+	PortfolioAllocation:     glidePathAllocation,
+	AllocationSchedule:      glidePathSchedule,
+	AllocationInterpolation: models.LinearAllocation,
+}
+
+var allocationScheduleCases = []AllocationScheduleTestCase{
+	{CaseName: "StepBeforeFirstWaypoint", ForecastRequest: stepGlidePathRequest, Year: 4, EquitiesWeight: 0.9},
+	{CaseName: "StepAtWaypoint", ForecastRequest: stepGlidePathRequest, Year: 5, EquitiesWeight: 0.4},
+	{CaseName: "StepBetweenWaypoints", ForecastRequest: stepGlidePathRequest, Year: 9, EquitiesWeight: 0.4},
+	{CaseName: "StepAfterLastWaypoint", ForecastRequest: stepGlidePathRequest, Year: 20, EquitiesWeight: 0.2},
+	{CaseName: "LinearFromPortfolioAllocation", ForecastRequest: linearGlidePathRequest, Year: 1, EquitiesWeight: 0.8},
+	{CaseName: "LinearBetweenWaypoints", ForecastRequest: linearGlidePathRequest, Year: 7, EquitiesWeight: 0.32},
+	{CaseName: "LinearAfterLastWaypoint", ForecastRequest: linearGlidePathRequest, Year: 20, EquitiesWeight: 0.2},
+}
+
+func TestGetScheduledWeights(t *testing.T) {
+	for _, test := range allocationScheduleCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			weights := getScheduledWeights(test.ForecastRequest, test.Year)
+			if !test_utils.AlmostEqual(weights[models.Equities], test.EquitiesWeight) ||
+				!test_utils.AlmostEqual(weights[models.Bonds], 1-test.EquitiesWeight) {
+				t.Errorf("expected %v of equities but got %v", test.EquitiesWeight, weights)
+			}
+		})
+	}
+}
+
+func TestRebalancingFollowsGlidePath(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 2,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {Allocation: 1.0},
+			models.Bonds:    {Allocation: 0.0},
+		},
+		AllocationSchedule: []models.AllocationWaypoint{
+			{Year: 2, Allocations: map[models.AssetType]float64{models.Equities: 0.5, models.Bonds: 0.5}},
+		},
+		AllocationInterpolation: models.LinearAllocation,
+		InitPortfolio:           models.Portfolio{models.Equities: models.Dollars(100_000)},
+		RebalancingStrategy:     models.EveryNYearsByAlloc,
+		RebalanceCadence:        1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expectedPortfolios := []models.Portfolio{
+		{models.Equities: models.Dollars(100_000)},
+		{models.Equities: models.Dollars(75_000), models.Bonds: models.Dollars(25_000)},
+		{models.Equities: models.Dollars(50_000), models.Bonds: models.Dollars(50_000)},
+	}
+	for year, expectedPortfolio := range expectedPortfolios {
+		for assetType, expectedVal := range expectedPortfolio {
+			if response.Portfolios[year][assetType] != expectedVal {
+				t.Errorf("expected %v in year %d but got %v", expectedPortfolio, year, response.Portfolios[year])
+			}
+		}
+	}
+}
+
+var allocationScheduleErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "YearsNotIncreasing",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: glidePathAllocation,
+			AllocationSchedule: []models.AllocationWaypoint{
+				{Year: 5, Allocations: map[models.AssetType]float64{models.Equities: 1.0}},
+				{Year: 5, Allocations: map[models.AssetType]float64{models.Bonds: 1.0}},
+			},
+		},
+		ErrorMessage: "allocation schedule years must be positive and increasing",
+	},
+	{
+		CaseName: "AssetNotInPortfolioAllocation",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: glidePathAllocation,
+			AllocationSchedule: []models.AllocationWaypoint{
+				{Year: 5, Allocations: map[models.AssetType]float64{models.Cash: 1.0}},
+			},
+		},
+		ErrorMessage: "allocation schedule assets must be in the portfolio allocation",
+	},
+	{
+		CaseName: "PercentsDoNotSumToOne",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: glidePathAllocation,
+			AllocationSchedule: []models.AllocationWaypoint{
+				{Year: 5, Allocations: map[models.AssetType]float64{models.Equities: 0.5}},
+			},
+		},
+		ErrorMessage: "allocation schedule percents must sum up to 1",
+	},
+}
+
+func TestAllocationScheduleErrorCases(t *testing.T) {
+	for _, test := range allocationScheduleErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
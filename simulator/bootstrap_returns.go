@@ -0,0 +1,61 @@
+package simulator
+
+import (
+	"math/rand/v2"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const defaultBootstrapBlockLength = 5
+
+// Builds a synthetic return sequence from randomly chosen blocks of consecutive historical years,
+// which preserves the serial correlation and fat tails of the historical data. Blocks wrap around
+// the end of the series so every year is equally likely to be drawn.
+type BootstrapReturns struct {
+	portfolioAllocation models.PortfolioAllocation
+	historicalReturns   []historicalReturn
+	blockLength         int
+	rng                 *rand.Rand
+	blockStartIdx       int
+}
+
+func (b *BootstrapReturns) NextYear(year int) (models.PortfolioAllocation, float64) {
+	blockOffset := (year - 1) % b.blockLength
+	if blockOffset == 0 {
+		b.blockStartIdx = b.rng.IntN(len(b.historicalReturns))
+	}
+	yearReturns := b.historicalReturns[(b.blockStartIdx+blockOffset)%len(b.historicalReturns)]
+	return applyHistoricalReturns(b.portfolioAllocation, yearReturns)
+}
+
+// Runs the forecast over many block bootstrapped paths and summarizes them the same way as the
+// Monte Carlo simulation
+func runBootstrapSimulation(
+	forecastRequest models.ForecastPortfolioRequest,
+	strategies simulationStrategies) ([]models.PercentileBand, float64, error) {
+
+	historicalReturns, err := loadHistoricalReturns()
+	if err != nil {
+		return nil, 0.0, err
+	}
+
+	blockLength := forecastRequest.BootstrapBlockLength
+	if blockLength == 0 {
+		blockLength = defaultBootstrapBlockLength
+	}
+
+	numSimulations := getNumSimulations(forecastRequest)
+	rng := rand.New(rand.NewPCG(forecastRequest.Seed, forecastRequest.Seed))
+	summary := newPathSummary(forecastRequest.EndYear)
+	for i := 0; i < numSimulations; i++ {
+		returnGenerator := &BootstrapReturns{
+			portfolioAllocation: forecastRequest.PortfolioAllocation,
+			historicalReturns:   historicalReturns,
+			blockLength:         blockLength,
+			rng:                 rng,
+		}
+		summary.add(simulatePortfolioPath(forecastRequest, strategies, returnGenerator))
+	}
+	percentileBands, successProbability := summary.summarize()
+	return percentileBands, successProbability, nil
+}
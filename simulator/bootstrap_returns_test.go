@@ -0,0 +1,75 @@
+package simulator
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+func TestBootstrapReturnsDrawConsecutiveBlocks(t *testing.T) {
+	historicalReturns, _ := loadHistoricalReturns()
+	generator := &BootstrapReturns{
+		portfolioAllocation: models.PortfolioAllocation{models.Equities: {Allocation: 1.0}},
+		historicalReturns:   historicalReturns,
+		blockLength:         4,
+		rng:                 rand.New(rand.NewPCG(3, 3)),
+	}
+
+	for year := 1; year <= 40; year++ {
+		allocation, inflationRate := generator.NextYear(year)
+		yearReturns := historicalReturns[(generator.blockStartIdx+(year-1)%4)%len(historicalReturns)]
+		if allocation[models.Equities].ReturnRate != yearReturns.returnRates[models.Equities] ||
+			inflationRate != yearReturns.inflationRate {
+			t.Fatalf("expected returns from %d in year %d but got %v", yearReturns.year, year, allocation)
+		}
+	}
+}
+
+var bootstrapRequest = models.ForecastPortfolioRequest{
+	EndYear: 30,
+	AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+		{Amount: models.Dollars(-5_000), StartYear: 1, EndYear: 30},
+	},
+	PortfolioAllocation: models.PortfolioAllocation{
+		models.Equities: {ReturnRate: 0.07, Allocation: 0.6},
+		models.Bonds:    {ReturnRate: 0.04, Allocation: 0.4},
+	},
+	InitPortfolio: models.Portfolio{
+		models.Equities: models.Dollars(60_000),
+		models.Bonds:    models.Dollars(40_000),
+	},
+	RebalanceCadence:     1,
+	RebalancingStrategy:  models.EveryNYearsByAlloc,
+	SimulationMode:       models.Bootstrap,
+	NumSimulations:       1_000,
+	Seed:                 11,
+	BootstrapBlockLength: 10,
+}
+
+func TestBootstrapSimulation(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(bootstrapRequest)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(response.PercentileBands) != bootstrapRequest.EndYear+1 {
+		t.Fatalf("expected %d bands but got %d", bootstrapRequest.EndYear+1, len(response.PercentileBands))
+	}
+	if response.SuccessProbability <= 0.0 || response.SuccessProbability >= 1.0 {
+		t.Errorf("expected some but not all paths to deplete but got %v", response.SuccessProbability)
+	}
+
+	again, _ := ForecastFuturePortfolioValueByYear(bootstrapRequest)
+	if again.SuccessProbability != response.SuccessProbability {
+		t.Errorf("expected seeded runs to match but got %v and %v", response.SuccessProbability, again.SuccessProbability)
+	}
+}
+
+func TestBootstrapSimulationRejectsInvalidBlockLength(t *testing.T) {
+	request := bootstrapRequest
+	request.BootstrapBlockLength = 1_000
+	_, err := ForecastFuturePortfolioValueByYear(request)
+	if err == nil || err.Error() != "bootstrap block length exceeds the available historical data" {
+		t.Errorf("expected block length error but got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package simulator
+
+import (
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const choleskyTolerance = 1e-10
+
+// Builds the correlation matrix between the portfolio's assets, in sorted order, followed by inflation
+// as the last row and column
+func buildCorrelationMatrix(forecastRequest models.ForecastPortfolioRequest) ([]models.AssetType, [][]float64, error) {
+	assetTypes := sortedAssetTypes(forecastRequest.PortfolioAllocation)
+	inflationIdx := len(assetTypes)
+
+	correlationMatrix := make([][]float64, inflationIdx+1)
+	for i := range correlationMatrix {
+		correlationMatrix[i] = make([]float64, inflationIdx+1)
+		correlationMatrix[i][i] = 1.0
+	}
+
+	if forecastRequest.CorrelationPreset == models.HistoricalCorrelation {
+		historicalReturns, err := loadHistoricalReturns()
+		if err != nil {
+			return nil, nil, err
+		}
+		series := make([][]float64, inflationIdx+1)
+		for _, yearReturns := range historicalReturns {
+			for i, assetType := range assetTypes {
+				series[i] = append(series[i], yearReturns.returnRates[assetType])
+			}
+			series[inflationIdx] = append(series[inflationIdx], yearReturns.inflationRate)
+		}
+		for i := range series {
+			for j := i + 1; j < len(series); j++ {
+				correlation := getPearsonCorrelation(series[i], series[j])
+				correlationMatrix[i][j] = correlation
+				correlationMatrix[j][i] = correlation
+			}
+		}
+	}
+
+	// Overrides were checked against the portfolio allocation when the request was validated
+	setCorrelation := func(i int, j int, correlation float64) {
+		if i != j {
+			correlationMatrix[i][j] = correlation
+			correlationMatrix[j][i] = correlation
+		}
+	}
+	for _, assetCorrelation := range forecastRequest.AssetCorrelations {
+		setCorrelation(
+			slices.Index(assetTypes, assetCorrelation.AssetType),
+			slices.Index(assetTypes, assetCorrelation.OtherAssetType),
+			assetCorrelation.Correlation)
+	}
+	for assetType, correlation := range forecastRequest.InflationCorrelations {
+		setCorrelation(slices.Index(assetTypes, assetType), inflationIdx, correlation)
+	}
+	return assetTypes, correlationMatrix, nil
+}
+
+// Factors a symmetric positive semi-definite matrix into a lower triangular matrix L where L * L^T
+// equals the input
+func choleskyDecomposition(matrix [][]float64) ([][]float64, error) {
+	size := len(matrix)
+	lower := make([][]float64, size)
+	for i := range lower {
+		lower[i] = make([]float64, size)
+	}
+
+	for j := 0; j < size; j++ {
+		diagonal := matrix[j][j]
+		for k := 0; k < j; k++ {
+			diagonal = diagonal - lower[j][k]*lower[j][k]
+		}
+		if diagonal < -choleskyTolerance {
+			return nil, errors.New("correlation matrix must be positive semi-definite")
+		}
+		remainders := make([]float64, size)
+		for i := j + 1; i < size; i++ {
+			remainders[i] = matrix[i][j]
+			for k := 0; k < j; k++ {
+				remainders[i] = remainders[i] - lower[i][k]*lower[j][k]
+			}
+		}
+		// A zero pivot means the factor is fully explained by the previous ones, so nothing may be
+		// left of its correlation with the factors after it either
+		if diagonal <= choleskyTolerance {
+			for i := j + 1; i < size; i++ {
+				if math.Abs(remainders[i]) > choleskyTolerance {
+					return nil, errors.New("correlation matrix must be positive semi-definite")
+				}
+			}
+			continue
+		}
+		lower[j][j] = math.Sqrt(diagonal)
+		for i := j + 1; i < size; i++ {
+			lower[i][j] = remainders[i] / lower[j][j]
+		}
+	}
+	return lower, nil
+}
+
+func getPearsonCorrelation(xs []float64, ys []float64) float64 {
+	xMean, yMean := 0.0, 0.0
+	for i := range xs {
+		xMean = xMean + xs[i]
+		yMean = yMean + ys[i]
+	}
+	xMean = xMean / float64(len(xs))
+	yMean = yMean / float64(len(ys))
+
+	covariance, xVariance, yVariance := 0.0, 0.0, 0.0
+	for i := range xs {
+		covariance = covariance + (xs[i]-xMean)*(ys[i]-yMean)
+		xVariance = xVariance + (xs[i]-xMean)*(xs[i]-xMean)
+		yVariance = yVariance + (ys[i]-yMean)*(ys[i]-yMean)
+	}
+	if xVariance == 0.0 || yVariance == 0.0 {
+		return 0.0
+	}
+	return covariance / math.Sqrt(xVariance*yVariance)
+}
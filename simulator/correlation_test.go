@@ -0,0 +1,97 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const matrixEqualityThreshold = 1e-9
+
+func TestCholeskyDecompositionReconstructsMatrix(t *testing.T) {
+	matrix := [][]float64{
+		{1.0, 0.5, 0.2},
+		{0.5, 1.0, -0.3},
+		{0.2, -0.3, 1.0},
+	}
+	lower, err := choleskyDecomposition(matrix)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for i := range matrix {
+		for j := range matrix {
+			reconstructed := 0.0
+			for k := range matrix {
+				reconstructed = reconstructed + lower[i][k]*lower[j][k]
+			}
+			if diff := reconstructed - matrix[i][j]; diff > matrixEqualityThreshold || diff < -matrixEqualityThreshold {
+				t.Errorf("expected %v at (%d, %d) but got %v", matrix[i][j], i, j, reconstructed)
+			}
+		}
+	}
+}
+
+func TestCholeskyDecompositionWithPerfectCorrelation(t *testing.T) {
+	lower, err := choleskyDecomposition([][]float64{
+		{1.0, 1.0},
+		{1.0, 1.0},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if lower[1][0] != 1.0 || lower[1][1] != 0.0 {
+		t.Errorf("expected the second factor to follow the first but got %v", lower)
+	}
+}
+
+func TestCholeskyDecompositionRejectsInvalidMatrix(t *testing.T) {
+	invalidMatrices := [][][]float64{
+		{
+			{1.0, 0.9, 0.9},
+			{0.9, 1.0, -0.9},
+			{0.9, -0.9, 1.0},
+		},
+		// Equities and bonds move together, so bonds can't correlate with cash unless equities do
+		{
+			{1.0, 1.0, 0.0},
+			{1.0, 1.0, 0.9},
+			{0.0, 0.9, 1.0},
+		},
+	}
+	for _, matrix := range invalidMatrices {
+		_, err := choleskyDecomposition(matrix)
+		if err == nil || err.Error() != "correlation matrix must be positive semi-definite" {
+			t.Errorf("expected positive semi-definite error for %v but got %v", matrix, err)
+		}
+	}
+}
+
+func TestBuildCorrelationMatrixFromHistoricalPreset(t *testing.T) {
+	forecastRequest := models.ForecastPortfolioRequest{
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {Allocation: 0.6},
+			models.Bonds:    {Allocation: 0.4},
+		},
+		CorrelationPreset: models.HistoricalCorrelation,
+		AssetCorrelations: []models.AssetCorrelation{
+			{AssetType: models.Bonds, OtherAssetType: models.Equities, Correlation: 0.25},
+		},
+	}
+	assetTypes, correlationMatrix, err := buildCorrelationMatrix(forecastRequest)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(assetTypes) != 2 || len(correlationMatrix) != 3 {
+		t.Fatalf("expected two assets and inflation but got %v", correlationMatrix)
+	}
+	if correlationMatrix[0][1] != 0.25 || correlationMatrix[1][0] != 0.25 {
+		t.Errorf("expected override of 0.25 but got %v", correlationMatrix)
+	}
+	// Historically, bond returns moved against inflation
+	if correlationMatrix[1][2] >= 0.0 {
+		t.Errorf("expected negative bond/inflation correlation but got %v", correlationMatrix[1][2])
+	}
+	if _, err := choleskyDecomposition(correlationMatrix); err != nil {
+		t.Errorf("expected historical preset to be decomposable but got %v", err)
+	}
+}
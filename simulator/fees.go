@@ -0,0 +1,81 @@
+package simulator
+
+import (
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Advisory fee on the household's assets, with each tier's rate applying to the assets above its
+// threshold. The tiers are applied in dollars and the total is rounded once.
+func getAdvisoryFee(forecastRequest models.ForecastPortfolioRequest, assetsUnderManagement models.Money) models.Money {
+	if assetsUnderManagement <= 0 {
+		return 0
+	}
+	tiers := forecastRequest.AdvisoryFeeTiers
+	if len(tiers) == 0 {
+		return assetsUnderManagement.MulRate(forecastRequest.AdvisoryFeeRate)
+	}
+	fee := 0.0
+	for i, tier := range tiers {
+		tierEnd := assetsUnderManagement
+		if i+1 < len(tiers) {
+			tierEnd = min(tierEnd, tiers[i+1].Threshold)
+		}
+		if tierEnd <= tier.Threshold {
+			break
+		}
+		fee = fee + (tierEnd-tier.Threshold).Dollars()*tier.Rate
+	}
+	return models.Dollars(fee)
+}
+
+// Deducts each asset's expense ratio and a share of the household's advisory fee in proportion to
+// the account's value for the fraction of a year, returning the fees paid
+func payFees(
+	accounts []models.Account,
+	forecastRequest models.ForecastPortfolioRequest,
+	portfolioAllocation models.PortfolioAllocation,
+	years float64) models.Money {
+
+	feesPaid := models.Money(0)
+	householdValue := models.Money(0)
+	accountValues := make([]models.Money, len(accounts))
+	for i := range accounts {
+		for assetType, assetVal := range accounts[i].Holdings {
+			if assetVal <= 0 {
+				continue
+			}
+			expense := assetVal.MulRate(portfolioAllocation[assetType].ExpenseRatio * years)
+			accounts[i].Holdings[assetType] = assetVal - expense
+			feesPaid = feesPaid + expense
+		}
+		accountValues[i], _, _ = getNetPortfolioValue(accounts[i].Holdings)
+		householdValue = householdValue + max(0, accountValues[i])
+	}
+
+	advisoryFee := getAdvisoryFee(forecastRequest, householdValue).MulRate(years)
+	if advisoryFee <= 0 {
+		return feesPaid
+	}
+	accountWeights := make([]float64, len(accounts))
+	for i, accountValue := range accountValues {
+		if accountValue > 0 {
+			accountWeights[i] = float64(accountValue) / float64(householdValue)
+		}
+	}
+	for i, accountFee := range splitAmount(advisoryFee, accountWeights) {
+		if accountValues[i] <= 0 {
+			continue
+		}
+		// Taking the fee from every asset in proportion to its value keeps the account's weights
+		// unchanged
+		assetTypes := sortedAssetTypes(accounts[i].Holdings)
+		assetWeights := make([]float64, len(assetTypes))
+		for j, assetType := range assetTypes {
+			assetWeights[j] = float64(accounts[i].Holdings[assetType]) / float64(accountValues[i])
+		}
+		for j, assetFee := range splitAmount(accountFee, assetWeights) {
+			accounts[i].Holdings[assetTypes[j]] = accounts[i].Holdings[assetTypes[j]] - assetFee
+		}
+	}
+	return feesPaid + advisoryFee
+}
@@ -0,0 +1,124 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type AdvisoryFeeTestCase struct {
+	CaseName              string
+	ForecastRequest       models.ForecastPortfolioRequest
+	AssetsUnderManagement float64
+	Fee                   float64
+}
+
+var tieredAdvisoryFees = []models.AdvisoryFeeTier{
+	{Threshold: 0, Rate: 0.01},
+	{Threshold: models.Dollars(1_000_000), Rate: 0.008},
+	{Threshold: models.Dollars(5_000_000), Rate: 0.005},
+}
+
+var advisoryFeeCases = []AdvisoryFeeTestCase{
+	{
+		CaseName:              "Flat",
+		ForecastRequest:       models.ForecastPortfolioRequest{AdvisoryFeeRate: 0.01},
+		AssetsUnderManagement: 500_000,
+		Fee:                   5_000,
+	},
+	{
+		CaseName:              "WithinFirstTier",
+		ForecastRequest:       models.ForecastPortfolioRequest{AdvisoryFeeTiers: tieredAdvisoryFees},
+		AssetsUnderManagement: 500_000,
+		Fee:                   5_000,
+	},
+	{
+		CaseName:              "AcrossTiers",
+		ForecastRequest:       models.ForecastPortfolioRequest{AdvisoryFeeTiers: tieredAdvisoryFees},
+		AssetsUnderManagement: 6_000_000,
+		Fee:                   10_000 + 32_000 + 5_000,
+	},
+	{
+		CaseName:              "NoAssets",
+		ForecastRequest:       models.ForecastPortfolioRequest{AdvisoryFeeRate: 0.01},
+		AssetsUnderManagement: -1_000,
+		Fee:                   0,
+	},
+}
+
+func TestGetAdvisoryFee(t *testing.T) {
+	for _, test := range advisoryFeeCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			if actual := getAdvisoryFee(test.ForecastRequest, models.Dollars(test.AssetsUnderManagement)); actual != models.Dollars(test.Fee) {
+				t.Errorf("expected %v but got %v", test.Fee, actual)
+			}
+		})
+	}
+}
+
+func TestForecastWithFees(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 2,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {ReturnRate: 0.1, Allocation: 0.5, ExpenseRatio: 0.01},
+			models.Bonds:    {Allocation: 0.5},
+		},
+		InitPortfolio:   models.Portfolio{models.Equities: models.Dollars(100_000), models.Bonds: models.Dollars(100_000)},
+		AdvisoryFeeRate: 0.01,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// Equities grow to 110000 and lose 1100 to their expense ratio, then 1% of the 208900 left goes
+	// to the advisor
+	expectedPortfolio := models.Portfolio{models.Equities: models.Dollars(108_900 * 0.99), models.Bonds: models.Dollars(99_000)}
+	for assetType, expectedVal := range expectedPortfolio {
+		if response.Portfolios[1][assetType] != expectedVal {
+			t.Errorf("expected %v but got %v", expectedPortfolio, response.Portfolios[1])
+		}
+	}
+	if response.FeesPaid[1] != models.Dollars(1_100+2_089) {
+		t.Errorf("expected 3189 of fees but got %v", response.FeesPaid[1])
+	}
+	if response.TotalFeesPaid != response.FeesPaid[1]+response.FeesPaid[2] {
+		t.Errorf("expected total fees to sum the yearly fees but got %v", response.TotalFeesPaid)
+	}
+}
+
+var feeErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "ExpenseRatioOutOfRange",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: models.PortfolioAllocation{models.Equities: {Allocation: 1.0, ExpenseRatio: -0.01}},
+		},
+		ErrorMessage: "expense ratio must be greater than or equal to 0 and less than 1",
+	},
+	{
+		CaseName: "FlatAndTieredFees",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			AdvisoryFeeRate:     0.01,
+			AdvisoryFeeTiers:    tieredAdvisoryFees,
+		},
+		ErrorMessage: "advisory fee rate and tiers can't both be set",
+	},
+	{
+		CaseName: "TiersNotStartingAtZero",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			AdvisoryFeeTiers:    []models.AdvisoryFeeTier{{Threshold: models.Dollars(1_000), Rate: 0.01}},
+		},
+		ErrorMessage: "advisory fee tiers must start at 0 and increase",
+	},
+}
+
+func TestFeeErrorCases(t *testing.T) {
+	for _, test := range feeErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package simulator
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Annual nominal returns for the S&P 500 including dividends, 10-year Treasury bonds and
+// 3-month Treasury bills along with December to December CPI-U inflation
+//
+//go:embed data/historical_returns.csv
+var historicalReturnsCsv string
+
+type historicalReturn struct {
+	year          int
+	returnRates   map[models.AssetType]float64
+	inflationRate float64
+}
+
+var loadHistoricalReturns = sync.OnceValues(func() ([]historicalReturn, error) {
+	records, err := csv.NewReader(strings.NewReader(historicalReturnsCsv)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read historical returns: %w", err)
+	}
+
+	historicalReturns := []historicalReturn{}
+	// Skip the header row
+	for _, record := range records[1:] {
+		year, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse historical year: %w", err)
+		}
+		rates := make([]float64, len(record)-1)
+		for i, field := range record[1:] {
+			rates[i], err = strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse historical returns for %d: %w", year, err)
+			}
+		}
+		historicalReturns = append(historicalReturns, historicalReturn{
+			year: year,
+			returnRates: map[models.AssetType]float64{
+				models.Equities: rates[0],
+				models.Bonds:    rates[1],
+				models.Cash:     rates[2],
+			},
+			inflationRate: rates[3],
+		})
+	}
+	return historicalReturns, nil
+})
+
+// Replays consecutive historical years, with the first simulated year using the returns of startIdx
+type HistoricalReturns struct {
+	portfolioAllocation models.PortfolioAllocation
+	historicalReturns   []historicalReturn
+	startIdx            int
+}
+
+func (h HistoricalReturns) NextYear(year int) (models.PortfolioAllocation, float64) {
+	return applyHistoricalReturns(h.portfolioAllocation, h.historicalReturns[h.startIdx+year-1])
+}
+
+func applyHistoricalReturns(
+	portfolioAllocation models.PortfolioAllocation,
+	yearReturns historicalReturn) (models.PortfolioAllocation, float64) {
+
+	historicalAllocation := models.PortfolioAllocation{}
+	for assetType, allocation := range portfolioAllocation {
+		// Assets without a historical series keep their expected return
+		if returnRate, ok := yearReturns.returnRates[assetType]; ok {
+			allocation.ReturnRate = returnRate
+		}
+		historicalAllocation[assetType] = allocation
+	}
+	return historicalAllocation, yearReturns.inflationRate
+}
+
+// Replays the forecast starting in every historical year that leaves enough data to reach the end year
+func runHistoricalBacktest(
+	forecastRequest models.ForecastPortfolioRequest,
+	strategies simulationStrategies) ([]models.HistoricalPeriod, []models.PercentileBand, float64, error) {
+
+	historicalReturns, err := loadHistoricalReturns()
+	if err != nil {
+		return nil, nil, 0.0, err
+	}
+	numPeriods := len(historicalReturns) - max(forecastRequest.EndYear, 1) + 1
+
+	historicalPeriods := make([]models.HistoricalPeriod, 0, numPeriods)
+	summary := newPathSummary(forecastRequest.EndYear)
+	for startIdx := 0; startIdx < numPeriods; startIdx++ {
+		path := simulatePortfolioPath(
+			forecastRequest,
+			strategies,
+			HistoricalReturns{
+				portfolioAllocation: forecastRequest.PortfolioAllocation,
+				historicalReturns:   historicalReturns,
+				startIdx:            startIdx,
+			})
+		endPortfolio := path.portfolios[len(path.portfolios)-1]
+		endPortfolioValue, _, _ := getNetPortfolioValue(endPortfolio)
+		historicalPeriods = append(historicalPeriods, models.HistoricalPeriod{
+			StartYear:         historicalReturns[startIdx].year,
+			EndPortfolio:      endPortfolio,
+			EndPortfolioValue: endPortfolioValue,
+			Survived:          !path.depleted,
+		})
+		summary.add(path)
+	}
+
+	percentileBands, successProbability := summary.summarize()
+	return historicalPeriods, percentileBands, successProbability, nil
+}
@@ -0,0 +1,103 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+func TestLoadHistoricalReturns(t *testing.T) {
+	historicalReturns, err := loadHistoricalReturns()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if historicalReturns[0].year != 1928 {
+		t.Errorf("expected data to start in 1928 but got %d", historicalReturns[0].year)
+	}
+	for i := 1; i < len(historicalReturns); i++ {
+		if historicalReturns[i].year != historicalReturns[i-1].year+1 {
+			t.Fatalf("expected consecutive years but got %d after %d", historicalReturns[i].year, historicalReturns[i-1].year)
+		}
+	}
+}
+
+var historicalRequest = models.ForecastPortfolioRequest{
+	EndYear: 1,
+	PortfolioAllocation: models.PortfolioAllocation{
+		models.Equities: {ReturnRate: 0.07, Allocation: 1.0},
+	},
+	InitPortfolio: models.Portfolio{
+		models.Equities: models.Dollars(100_000),
+	},
+	RebalancingStrategy: models.YearlyToZero,
+	SimulationMode:      models.Historical,
+}
+
+func TestHistoricalBacktestUsesHistoricalReturns(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(historicalRequest)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	historicalReturns, _ := loadHistoricalReturns()
+	if len(response.HistoricalPeriods) != len(historicalReturns) {
+		t.Fatalf("expected %d periods but got %d", len(historicalReturns), len(response.HistoricalPeriods))
+	}
+
+	// 1928 had a 43.81% nominal return with -1% inflation
+	firstPeriod := response.HistoricalPeriods[0]
+	if firstPeriod.StartYear != 1928 || firstPeriod.EndPortfolioValue != models.Dollars(144_810) {
+		t.Errorf("expected 1928 to end at 144810 but got %v", firstPeriod)
+	}
+	if response.SuccessProbability != 1.0 {
+		t.Errorf("expected every period to survive without withdrawals but got %v", response.SuccessProbability)
+	}
+}
+
+func TestHistoricalBacktestReportsFailedPeriods(t *testing.T) {
+	request := historicalRequest
+	request.EndYear = 30
+	request.AnnualPortfolioBalanceChanges = []models.AnnualPortfolioBalanceChange{
+		{Amount: models.Dollars(-6_000), StartYear: 1, EndYear: 30},
+	}
+	response, _ := ForecastFuturePortfolioValueByYear(request)
+
+	survivedByStartYear := map[int]bool{}
+	for _, period := range response.HistoricalPeriods {
+		survivedByStartYear[period.StartYear] = period.Survived
+	}
+	if survivedByStartYear[1966] {
+		t.Errorf("expected a 6%% withdrawal starting in 1966 to fail")
+	}
+	if !survivedByStartYear[1982] {
+		t.Errorf("expected a 6%% withdrawal starting in 1982 to survive")
+	}
+	if response.SuccessProbability <= 0.0 || response.SuccessProbability >= 1.0 {
+		t.Errorf("expected some but not all periods to survive but got %v", response.SuccessProbability)
+	}
+}
+
+func TestHistoricalBacktestBeyondAvailableData(t *testing.T) {
+	request := historicalRequest
+	request.EndYear = 120
+	_, err := ForecastFuturePortfolioValueByYear(request)
+	if err == nil || err.Error() != "end year exceeds the available historical data" {
+		t.Errorf("expected historical data error but got %v", err)
+	}
+}
+
+func TestHistoricalBacktestKeepsExpectedReturnOfCustomAssets(t *testing.T) {
+	request := historicalRequest
+	request.PortfolioAllocation = models.PortfolioAllocation{
+		"Private Fund": {ReturnRate: 0.1, Allocation: 1.0},
+	}
+	request.InitPortfolio = models.Portfolio{"Private Fund": models.Dollars(100_000)}
+	response, err := ForecastFuturePortfolioValueByYear(request)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// 1928 had -1% inflation so the 10% nominal return is 11% in real terms
+	firstPeriod := response.HistoricalPeriods[0]
+	if firstPeriod.EndPortfolioValue != models.Dollars(111_000) {
+		t.Errorf("expected 1928 to end at 111000 but got %v", firstPeriod)
+	}
+}
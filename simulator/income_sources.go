@@ -0,0 +1,134 @@
+package simulator
+
+import (
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Real payment from the income source in the year, given the realized inflation of each year so far
+func getIncomeSourcePayment(
+	incomeSource models.IncomeSource,
+	forecastRequest models.ForecastPortfolioRequest,
+	inflationRates []float64,
+	year int) models.Money {
+
+	age := getCalendarYear(forecastRequest, year) - incomeSource.BirthYear
+	if age < incomeSource.StartAge {
+		return 0
+	}
+	payment := incomeSource.AnnualAmount.Dollars()
+	if incomeSource.DeathAge != 0 && age >= incomeSource.DeathAge {
+		payment = payment * incomeSource.SurvivorFraction
+	}
+	if !incomeSource.HasCola {
+		startYear := incomeSource.BirthYear + incomeSource.StartAge - forecastRequest.CurrentYear
+		for inflationYear := max(startYear+1, 1); inflationYear <= year; inflationYear++ {
+			payment = payment / (1 + inflationRates[inflationYear])
+		}
+	}
+	return models.Dollars(payment)
+}
+
+// Payments from each income source in the year along with the part of their total that's taxed
+func getIncomeSourcePayments(
+	forecastRequest models.ForecastPortfolioRequest,
+	inflationRates []float64,
+	year int) ([]models.IncomeSourcePayment, models.Money) {
+
+	payments := []models.IncomeSourcePayment{}
+	taxableAmount := models.Money(0)
+	for _, incomeSource := range forecastRequest.IncomeSources {
+		payment := getIncomeSourcePayment(incomeSource, forecastRequest, inflationRates, year)
+		if payment > 0 {
+			payments = append(payments, models.IncomeSourcePayment{IncomeSourceName: incomeSource.Name, Amount: payment})
+			taxableAmount = taxableAmount + payment.MulRate(getTaxableFraction(incomeSource, forecastRequest))
+		}
+	}
+	return payments, taxableAmount
+}
+
+// Taxed fraction of each payment from the income source. An annuity's payments follow the account
+// its premium came from: fully taxed from a tax-deferred account, untaxed from a tax-free or health
+// savings account, and taxed beyond the exclusion ratio when bought with after-tax money.
+func getTaxableFraction(incomeSource models.IncomeSource, forecastRequest models.ForecastPortfolioRequest) float64 {
+	if incomeSource.IncomeSourceType != models.Annuity {
+		return 1.0
+	}
+	accountIdx := findAccount(forecastRequest.Accounts, incomeSource.PremiumAccountName)
+	if incomeSource.PremiumAccountName != "" && accountIdx >= 0 {
+		switch forecastRequest.Accounts[accountIdx].AccountType {
+		case models.TaxDeferred:
+			return 1.0
+		case models.TaxFree, models.HealthSavings:
+			return 0.0
+		}
+	}
+	return 1.0 - getExclusionRatio(incomeSource)
+}
+
+func getExclusionRatio(incomeSource models.IncomeSource) float64 {
+	if incomeSource.Premium <= 0 {
+		return 0.0
+	}
+	expectedYears := float64(incomeSource.DeathAge - incomeSource.StartAge)
+	if incomeSource.DeathAge == 0 {
+		expectedYears = getRmdStyleDistributionPeriod(uniformLifetimeTable, incomeSource.StartAge)
+	}
+	expectedReturn := incomeSource.AnnualAmount.Dollars() * expectedYears
+	if expectedReturn <= 0 {
+		return 1.0
+	}
+	return min(1.0, incomeSource.Premium.Dollars()/expectedReturn)
+}
+
+// Pays the premiums of the annuities bought in the year, returning the premiums left for the
+// household's withdrawal order along with the withdrawals from named accounts and the part of their
+// premiums those accounts couldn't cover. Annuities bought before the forecast starts are already
+// paid for.
+func payAnnuityPremiums(
+	accounts []models.Account,
+	forecastRequest models.ForecastPortfolioRequest,
+	year int,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) (householdPremiums models.Money, withdrawals []models.AccountWithdrawal, unfundedAmount models.Money) {
+
+	for _, incomeSource := range forecastRequest.IncomeSources {
+		purchaseYear := incomeSource.BirthYear + incomeSource.PurchaseAge
+		if incomeSource.IncomeSourceType != models.Annuity || purchaseYear != getCalendarYear(forecastRequest, year) {
+			continue
+		}
+		accountIdx := findAccount(accounts, incomeSource.PremiumAccountName)
+		if incomeSource.PremiumAccountName == "" || accountIdx < 0 {
+			householdPremiums = householdPremiums + incomeSource.Premium
+		} else if accounts[accountIdx].AccountType == models.TaxDeferred {
+			unfundedAmount = unfundedAmount + incomeSource.Premium -
+				transferFromAccount(&accounts[accountIdx], incomeSource.Premium, portfolioAllocation)
+		} else {
+			withdrawal := withdrawFromAccount(
+				&accounts[accountIdx], incomeSource.Premium, unlimitedAmount, portfolioAllocation, income)
+			withdrawals = recordWithdrawal(withdrawals, withdrawal)
+			unfundedAmount = unfundedAmount + incomeSource.Premium - withdrawal.NetAmount
+		}
+	}
+	return householdPremiums, withdrawals, unfundedAmount
+}
+
+// Moves as much of the amount as the account holds out of it without realizing any income, returning
+// the amount moved
+func transferFromAccount(
+	account *models.Account,
+	amount models.Money,
+	portfolioAllocation models.PortfolioAllocation) models.Money {
+
+	accountValue, _, _ := getNetPortfolioValue(account.Holdings)
+	if accountValue <= 0 || amount <= 0 {
+		return 0
+	}
+	if amount >= accountValue {
+		account.Holdings = emptyPortfolio(account.Holdings)
+		account.CostBasis = 0
+		return accountValue
+	}
+	spreadAmountByAllocation(account, -amount, portfolioAllocation)
+	account.CostBasis = account.CostBasis.MulRate(1 - float64(amount)/float64(accountValue))
+	return amount
+}
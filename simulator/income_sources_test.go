@@ -0,0 +1,260 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type IncomeSourcePaymentTestCase struct {
+	CaseName     string
+	IncomeSource models.IncomeSource
+	Year         int
+	Payment      float64
+}
+
+// Forecasts start in 2024 with 10% inflation every year
+var incomeSourcePaymentCases = []IncomeSourcePaymentTestCase{
+	{
+		CaseName:     "BeforeStartAge",
+		IncomeSource: models.IncomeSource{AnnualAmount: models.Dollars(20_000), BirthYear: 1960, StartAge: 66},
+		Year:         1,
+		Payment:      0,
+	},
+	{
+		CaseName:     "FirstPayment",
+		IncomeSource: models.IncomeSource{AnnualAmount: models.Dollars(20_000), BirthYear: 1960, StartAge: 65},
+		Year:         1,
+		Payment:      20_000,
+	},
+	{
+		CaseName:     "WithoutColaLosesValueAfterStarting",
+		IncomeSource: models.IncomeSource{AnnualAmount: models.Dollars(20_000), BirthYear: 1960, StartAge: 65},
+		Year:         3,
+		Payment:      20_000 / 1.1 / 1.1,
+	},
+	{
+		CaseName:     "WithColaKeepsValue",
+		IncomeSource: models.IncomeSource{AnnualAmount: models.Dollars(20_000), BirthYear: 1960, StartAge: 65, HasCola: true},
+		Year:         3,
+		Payment:      20_000,
+	},
+	{
+		CaseName:     "AlreadyStartedLosesValueFromFirstYear",
+		IncomeSource: models.IncomeSource{AnnualAmount: models.Dollars(20_000), BirthYear: 1955, StartAge: 65},
+		Year:         1,
+		Payment:      20_000 / 1.1,
+	},
+	{
+		CaseName: "Survivor",
+		IncomeSource: models.IncomeSource{
+			AnnualAmount:     models.Dollars(20_000),
+			BirthYear:        1960,
+			StartAge:         65,
+			HasCola:          true,
+			DeathAge:         66,
+			SurvivorFraction: 0.5,
+		},
+		Year:    2,
+		Payment: 10_000,
+	},
+}
+
+func TestGetIncomeSourcePayment(t *testing.T) {
+	inflationRates := []float64{0.0, 0.1, 0.1, 0.1}
+	for _, test := range incomeSourcePaymentCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			forecastRequest := models.ForecastPortfolioRequest{CurrentYear: 2024}
+			actual := getIncomeSourcePayment(test.IncomeSource, forecastRequest, inflationRates, test.Year)
+			if actual != models.Dollars(test.Payment) {
+				t.Errorf("expected %v but got %v", test.Payment, actual)
+			}
+		})
+	}
+}
+
+func TestForecastWithAnnuity(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear:             2,
+		PortfolioAllocation: singleAssetAllocation,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(200_000)},
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-10_000), StartYear: 2, EndYear: 2},
+		},
+		TaxRates:    models.TaxRates{OrdinaryIncomeRate: 0.25},
+		CurrentYear: 2024,
+		IncomeSources: []models.IncomeSource{
+			{
+				Name:             "Deferred Annuity",
+				IncomeSourceType: models.Annuity,
+				AnnualAmount:     models.Dollars(8_000),
+				BirthYear:        1960,
+				StartAge:         66,
+				HasCola:          true,
+				Premium:          models.Dollars(100_000),
+				PurchaseAge:      65,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if response.Portfolios[1][models.Equities] != models.Dollars(100_000) {
+		t.Errorf("expected the premium to be withdrawn but got %v", response.Portfolios[1])
+	}
+	if response.GuaranteedIncome[2] != models.Dollars(8_000) {
+		t.Errorf("expected 8000 of guaranteed income but got %v", response.GuaranteedIncome)
+	}
+	if len(response.IncomeSourcePayments[2]) != 1 || response.IncomeSourcePayments[2][0].IncomeSourceName != "Deferred Annuity" {
+		t.Errorf("expected a payment from the annuity but got %v", response.IncomeSourcePayments[2])
+	}
+	// The premium returns 100000 / (8000 * 33.4) of each payment untaxed, so 6748.5 is left after tax
+	// for spending and the rest comes from the portfolio
+	if response.Portfolios[2][models.Equities] != models.Dollars(96_748.5) {
+		t.Errorf("expected 96748.5 but got %v", response.Portfolios[2])
+	}
+}
+
+type AnnuityTaxTestCase struct {
+	CaseName   string
+	Account    models.Account
+	PremiumTax models.Money
+	PaymentTax models.Money
+}
+
+var annuityTaxCases = []AnnuityTaxTestCase{
+	{
+		CaseName: "QualifiedPremiumIsUntaxedAndPaymentsAreTaxedInFull",
+		Account: models.Account{
+			Name:        "401k",
+			AccountType: models.TaxDeferred,
+			Holdings:    models.Portfolio{models.Equities: models.Dollars(200_000)},
+		},
+		PremiumTax: 0,
+		PaymentTax: models.Dollars(2_000),
+	},
+	{
+		// Half of the premium's gross up is a gain, and half of each payment returns the premium over
+		// the 20 years expected until the death age
+		CaseName: "AfterTaxPremiumIsTaxedOnGainsAndPaymentsOnTheirGain",
+		Account: models.Account{
+			Name:        "401k",
+			AccountType: models.Taxable,
+			Holdings:    models.Portfolio{models.Equities: models.Dollars(200_000)},
+			CostBasis:   models.Dollars(150_000),
+		},
+		PremiumTax: models.Dollars(5_263.16),
+		PaymentTax: models.Dollars(1_000),
+	},
+	{
+		CaseName: "TaxFreePremiumIsUntaxedAndSoArePayments",
+		Account: models.Account{
+			Name:        "401k",
+			AccountType: models.TaxFree,
+			Holdings:    models.Portfolio{models.Equities: models.Dollars(200_000)},
+		},
+		PremiumTax: 0,
+		PaymentTax: 0,
+	},
+}
+
+func TestAnnuityTaxes(t *testing.T) {
+	for _, test := range annuityTaxCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+				EndYear:             1,
+				PortfolioAllocation: singleAssetAllocation,
+				Accounts:            []models.Account{test.Account},
+				TaxRates:            models.TaxRates{OrdinaryIncomeRate: 0.2, CapitalGainsRate: 0.2},
+				CurrentYear:         2024,
+				IncomeSources: []models.IncomeSource{
+					{
+						Name:               "SPIA",
+						IncomeSourceType:   models.Annuity,
+						AnnualAmount:       models.Dollars(10_000),
+						BirthYear:          1960,
+						StartAge:           65,
+						DeathAge:           85,
+						HasCola:            true,
+						Premium:            models.Dollars(100_000),
+						PurchaseAge:        65,
+						PremiumAccountName: "401k",
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			premiumTax := models.Money(0)
+			for _, withdrawal := range response.AccountWithdrawals[1] {
+				premiumTax = premiumTax + withdrawal.TaxPaid
+			}
+			if paymentTax := response.TaxesPaid[1] - premiumTax; premiumTax != test.PremiumTax || paymentTax != test.PaymentTax {
+				t.Errorf("expected %v of tax on the premium and %v on the payments but got %v and %v",
+					test.PremiumTax, test.PaymentTax, premiumTax, paymentTax)
+			}
+		})
+	}
+}
+
+var incomeSourceErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "NegativePremium",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			CurrentYear:         2024,
+			IncomeSources:       []models.IncomeSource{{IncomeSourceType: models.Annuity, Premium: models.Dollars(-1), BirthYear: 1960}},
+		},
+		ErrorMessage: "income source amounts must be greater than or equal to 0",
+	},
+	{
+		CaseName: "SurvivorFractionOutOfRange",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			CurrentYear:         2024,
+			IncomeSources:       []models.IncomeSource{{SurvivorFraction: 1.5, BirthYear: 1960}},
+		},
+		ErrorMessage: "survivor fraction must be between 0 and 1",
+	},
+	{
+		CaseName: "BirthYearAfterCurrentYear",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			IncomeSources:       []models.IncomeSource{{BirthYear: 1960}},
+		},
+		ErrorMessage: "income source birth year must be less than or equal to the current year",
+	},
+	{
+		CaseName: "UnknownPremiumAccount",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			CurrentYear:         2024,
+			IncomeSources: []models.IncomeSource{
+				{IncomeSourceType: models.Annuity, BirthYear: 1960, PremiumAccountName: "IRA"},
+			},
+		},
+		ErrorMessage: "annuity premium account must be one of the accounts",
+	},
+	{
+		CaseName: "AnnuityStartsBeforePurchase",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			CurrentYear:         2024,
+			IncomeSources: []models.IncomeSource{
+				{IncomeSourceType: models.Annuity, BirthYear: 1960, StartAge: 64, PurchaseAge: 65},
+			},
+		},
+		ErrorMessage: "annuity payments must start at or after the purchase age",
+	},
+}
+
+func TestIncomeSourceErrorCases(t *testing.T) {
+	for _, test := range incomeSourceErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
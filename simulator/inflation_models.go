@@ -0,0 +1,90 @@
+package simulator
+
+import (
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Provides the inflation rate for a given simulated year. The shock is a standard normal draw that
+// stochastic simulations correlate with asset returns and is 0 for deterministic forecasts.
+type InflationModel interface {
+	NextYear(year int, shock float64) float64
+}
+
+type ConstantInflationModel struct {
+	inflationRate       float64
+	inflationVolatility float64
+}
+
+func (c ConstantInflationModel) NextYear(year int, shock float64) float64 {
+	return c.inflationRate + shock*c.inflationVolatility
+}
+
+// AR(1) process where each year keeps a fraction of the previous year's deviation from the mean
+type MeanRevertingInflationModel struct {
+	meanInflationRate   float64
+	meanReversion       float64
+	inflationVolatility float64
+	prevInflationRate   float64
+}
+
+func (m *MeanRevertingInflationModel) NextYear(year int, shock float64) float64 {
+	inflationRate := m.meanInflationRate +
+		m.meanReversion*(m.prevInflationRate-m.meanInflationRate) +
+		shock*m.inflationVolatility
+	m.prevInflationRate = inflationRate
+	return inflationRate
+}
+
+// Replays consecutive historical inflation rates, wrapping around the end of the series
+type HistoricalInflationModel struct {
+	historicalReturns []historicalReturn
+	startIdx          int
+}
+
+func (h HistoricalInflationModel) NextYear(year int, shock float64) float64 {
+	return h.historicalReturns[(h.startIdx+year-1)%len(h.historicalReturns)].inflationRate
+}
+
+type ScheduledInflationModel struct {
+	inflationSchedule []float64
+}
+
+func (s ScheduledInflationModel) NextYear(year int, shock float64) float64 {
+	return s.inflationSchedule[year-1]
+}
+
+// Builds a fresh inflation model for a single path since some models carry state between years
+func newInflationModel(forecastRequest models.ForecastPortfolioRequest) (InflationModel, error) {
+	switch forecastRequest.InflationModel {
+	case models.HistoricalInflation:
+		historicalReturns, err := loadHistoricalReturns()
+		if err != nil {
+			return nil, err
+		}
+		startIdx := forecastRequest.HistoricalInflationStartYear - historicalReturns[0].year
+		if startIdx < 0 || startIdx >= len(historicalReturns) {
+			return nil, newValidationError("HistoricalInflationStartYear", models.OutOfRange, "historical inflation start year must be within the available historical data")
+		}
+		return HistoricalInflationModel{historicalReturns: historicalReturns, startIdx: startIdx}, nil
+	case models.MeanRevertingInflation:
+		if forecastRequest.InflationMeanReversion < 0 || forecastRequest.InflationMeanReversion > 1 {
+			return nil, newValidationError("InflationMeanReversion", models.OutOfRange, "inflation mean reversion must be between 0 and 1")
+		}
+		return &MeanRevertingInflationModel{
+			meanInflationRate:   forecastRequest.AnnualInflationRate,
+			meanReversion:       forecastRequest.InflationMeanReversion,
+			inflationVolatility: forecastRequest.AnnualInflationVolatility,
+			prevInflationRate:   forecastRequest.InitialInflationRate,
+		}, nil
+	case models.ScheduledInflation:
+		if len(forecastRequest.InflationSchedule) < forecastRequest.EndYear {
+			return nil, newValidationError("InflationSchedule", models.MissingValue, "inflation schedule must cover every year up to the end year")
+		}
+		return ScheduledInflationModel{inflationSchedule: forecastRequest.InflationSchedule}, nil
+	default:
+		return ConstantInflationModel{
+			inflationRate:       forecastRequest.AnnualInflationRate,
+			inflationVolatility: forecastRequest.AnnualInflationVolatility,
+		}, nil
+	}
+}
@@ -0,0 +1,149 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const rateEqualityThreshold = 1e-9
+
+type InflationModelTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	InflationRates  []float64
+}
+
+var inflationModelCases = []InflationModelTestCase{
+	{
+		CaseName: "Constant",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             3,
+			AnnualInflationRate: 0.03,
+		},
+		InflationRates: []float64{0.03, 0.03, 0.03},
+	},
+	{
+		CaseName: "MeanReverting",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:                3,
+			AnnualInflationRate:    0.03,
+			InflationModel:         models.MeanRevertingInflation,
+			InflationMeanReversion: 0.5,
+			InitialInflationRate:   0.09,
+		},
+		InflationRates: []float64{0.06, 0.045, 0.0375},
+	},
+	{
+		CaseName: "Historical",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:                      3,
+			InflationModel:               models.HistoricalInflation,
+			HistoricalInflationStartYear: 1973,
+		},
+		InflationRates: []float64{0.087, 0.123, 0.069},
+	},
+	{
+		CaseName: "HistoricalWrapsAround",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:                      2,
+			InflationModel:               models.HistoricalInflation,
+			HistoricalInflationStartYear: 2023,
+		},
+		InflationRates: []float64{0.034, -0.01},
+	},
+	{
+		CaseName: "Scheduled",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:           3,
+			InflationModel:    models.ScheduledInflation,
+			InflationSchedule: []float64{0.08, 0.05, 0.02, 0.01},
+		},
+		InflationRates: []float64{0.08, 0.05, 0.02},
+	},
+}
+
+func TestInflationModels(t *testing.T) {
+	for _, test := range inflationModelCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			inflationModel, err := newInflationModel(test.ForecastRequest)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			for i, expectedRate := range test.InflationRates {
+				actualRate := inflationModel.NextYear(i+1, 0.0)
+				if math.Abs(actualRate-expectedRate) > rateEqualityThreshold {
+					t.Errorf("expected %v in year %d but got %v", expectedRate, i+1, actualRate)
+				}
+			}
+		})
+	}
+}
+
+var inflationModelErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "HistoricalStartYearOutOfRange",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			InflationModel:               models.HistoricalInflation,
+			HistoricalInflationStartYear: 1800,
+		},
+		ErrorMessage: "historical inflation start year must be within the available historical data",
+	},
+	{
+		CaseName: "MeanReversionOutOfRange",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			InflationModel:         models.MeanRevertingInflation,
+			InflationMeanReversion: 1.5,
+		},
+		ErrorMessage: "inflation mean reversion must be between 0 and 1",
+	},
+	{
+		CaseName: "ScheduleTooShort",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:           3,
+			InflationModel:    models.ScheduledInflation,
+			InflationSchedule: []float64{0.02},
+		},
+		ErrorMessage: "inflation schedule must cover every year up to the end year",
+	},
+}
+
+func TestInflationModelErrorCases(t *testing.T) {
+	for _, test := range inflationModelErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := newInflationModel(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
+
+func TestForecastWithScheduledInflation(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 2,
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-10_000), StartYear: 1, EndYear: 2},
+		},
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {ReturnRate: 0.05, Allocation: 1.0},
+		},
+		InitPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(100_000),
+		},
+		InflationModel:    models.ScheduledInflation,
+		InflationSchedule: []float64{0.10, 0.0},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(response.InflationRates) != 3 || response.InflationRates[1] != 0.10 {
+		t.Errorf("expected realized inflation to be reported but got %v", response.InflationRates)
+	}
+	// Year 1 loses 5% in real terms, year 2 gains 5%
+	expectedVal := (100_000*0.95-10_000)*1.05 - 10_000
+	if actualVal := response.Portfolios[2][models.Equities]; actualVal != models.Dollars(expectedVal) {
+		t.Errorf("expected %v but got %v", expectedVal, actualVal)
+	}
+}
@@ -0,0 +1,102 @@
+package simulator
+
+import (
+	"math/rand/v2"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const defaultNumSimulations = 10_000
+
+// Keeps a single request from tying up the server
+const maxNumSimulations = 100_000
+
+// Runs the forecast over many paths with randomly drawn, correlated returns and summarizes the
+// distribution of total portfolio values for each year along with the fraction of
+// paths that were never depleted
+func runMonteCarloSimulation(
+	forecastRequest models.ForecastPortfolioRequest,
+	strategies simulationStrategies) ([]models.PercentileBand, float64, error) {
+
+	numSimulations := getNumSimulations(forecastRequest)
+	rng := rand.New(rand.NewPCG(forecastRequest.Seed, forecastRequest.Seed))
+	returnGenerator, err := newNormalReturns(forecastRequest, rng)
+	if err != nil {
+		return nil, 0.0, err
+	}
+
+	summary := newPathSummary(forecastRequest.EndYear)
+	for i := 0; i < numSimulations; i++ {
+		// Only the inflation model carries state between years
+		returnGenerator.inflationModel, _ = newInflationModel(forecastRequest)
+		summary.add(simulatePortfolioPath(forecastRequest, strategies, returnGenerator))
+	}
+	percentileBands, successProbability := summary.summarize()
+	return percentileBands, successProbability, nil
+}
+
+func getNumSimulations(forecastRequest models.ForecastPortfolioRequest) int {
+	if forecastRequest.NumSimulations == 0 {
+		return defaultNumSimulations
+	}
+	return forecastRequest.NumSimulations
+}
+
+// Keeps only the total portfolio value of each year of each path, so the paths themselves can be
+// dropped as soon as they're simulated
+type pathSummary struct {
+	portfolioValuesByYear [][]models.Money
+	numPaths              int
+	numSuccessfulPaths    int
+}
+
+func newPathSummary(endYear int) *pathSummary {
+	return &pathSummary{portfolioValuesByYear: make([][]models.Money, endYear+1)}
+}
+
+func (p *pathSummary) add(path portfolioPath) {
+	p.numPaths++
+	if !path.depleted {
+		p.numSuccessfulPaths++
+	}
+	for year, portfolio := range path.portfolios {
+		portfolioValue, _, _ := getNetPortfolioValue(portfolio)
+		p.portfolioValuesByYear[year] = append(p.portfolioValuesByYear[year], portfolioValue)
+	}
+}
+
+// Computes per-year percentile bands of the total portfolio value across paths and the
+// fraction of paths that were never depleted
+func (p *pathSummary) summarize() ([]models.PercentileBand, float64) {
+	percentileBands := make([]models.PercentileBand, 0, len(p.portfolioValuesByYear))
+	for year, portfolioValues := range p.portfolioValuesByYear {
+		slices.Sort(portfolioValues)
+		percentileBands = append(percentileBands, models.PercentileBand{
+			Year:         year,
+			Percentile5:  getPercentile(portfolioValues, 0.05),
+			Percentile25: getPercentile(portfolioValues, 0.25),
+			Percentile50: getPercentile(portfolioValues, 0.50),
+			Percentile75: getPercentile(portfolioValues, 0.75),
+			Percentile95: getPercentile(portfolioValues, 0.95),
+		})
+	}
+	if p.numPaths == 0 {
+		return percentileBands, 0.0
+	}
+	return percentileBands, float64(p.numSuccessfulPaths) / float64(p.numPaths)
+}
+
+// Linearly interpolates between the closest ranks of the already sorted values
+func getPercentile(sortedValues []models.Money, percentile float64) models.Money {
+	if len(sortedValues) == 0 {
+		return 0
+	}
+	rank := percentile * float64(len(sortedValues)-1)
+	lowerIdx := int(rank)
+	if lowerIdx >= len(sortedValues)-1 {
+		return sortedValues[len(sortedValues)-1]
+	}
+	weight := rank - float64(lowerIdx)
+	return models.Dollars(sortedValues[lowerIdx].Dollars()*(1-weight) + sortedValues[lowerIdx+1].Dollars()*weight)
+}
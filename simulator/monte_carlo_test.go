@@ -0,0 +1,140 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+var monteCarloRequest = models.ForecastPortfolioRequest{
+	EndYear:             10,
+	AnnualInflationRate: 0.02,
+	AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+		{
+			Amount:          models.Dollars(10_000),
+			StartYear:       0,
+			EndYear:         10,
+			AnnualPctChange: 0.0,
+		},
+	},
+	PortfolioAllocation: models.PortfolioAllocation{
+		models.Equities: {
+			ReturnRate: 0.07,
+			Allocation: 0.6,
+			Volatility: 0.15,
+		},
+		models.Bonds: {
+			ReturnRate: 0.04,
+			Allocation: 0.4,
+			Volatility: 0.05,
+		},
+	},
+	InitPortfolio: models.Portfolio{
+		models.Equities: models.Dollars(120_000),
+		models.Bonds:    models.Dollars(80_000),
+	},
+	RebalanceCadence:    1,
+	RebalancingStrategy: models.EveryNYearsByAlloc,
+	SimulationMode:      models.MonteCarlo,
+	NumSimulations:      2_000,
+	Seed:                42,
+}
+
+func TestMonteCarloPercentileBands(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(monteCarloRequest)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(response.PercentileBands) != monteCarloRequest.EndYear+1 {
+		t.Fatalf("expected %d bands but got %d", monteCarloRequest.EndYear+1, len(response.PercentileBands))
+	}
+
+	initBand := response.PercentileBands[0]
+	if initBand.Percentile5 != models.Dollars(200_000) || initBand.Percentile95 != models.Dollars(200_000) {
+		t.Errorf("expected initial band to equal the initial portfolio but got %v", initBand)
+	}
+
+	for _, band := range response.PercentileBands {
+		if band.Percentile5 > band.Percentile25 ||
+			band.Percentile25 > band.Percentile50 ||
+			band.Percentile50 > band.Percentile75 ||
+			band.Percentile75 > band.Percentile95 {
+			t.Errorf("expected ordered percentiles but got %v", band)
+		}
+	}
+
+	endBand := response.PercentileBands[monteCarloRequest.EndYear]
+	if endBand.Percentile95-endBand.Percentile5 < models.Dollars(10_000) {
+		t.Errorf("expected volatility to spread out the end year band but got %v", endBand)
+	}
+}
+
+func TestMonteCarloIsReproducibleWithSeed(t *testing.T) {
+	first, _ := ForecastFuturePortfolioValueByYear(monteCarloRequest)
+	second, _ := ForecastFuturePortfolioValueByYear(monteCarloRequest)
+	for i := range first.PercentileBands {
+		if first.PercentileBands[i] != second.PercentileBands[i] {
+			t.Fatalf("expected %v but got %v", first.PercentileBands[i], second.PercentileBands[i])
+		}
+	}
+}
+
+func TestMonteCarloWithoutVolatilityMatchesDeterministicForecast(t *testing.T) {
+	request := monteCarloRequest
+	request.PortfolioAllocation = models.PortfolioAllocation{
+		models.Equities: {ReturnRate: 0.07, Allocation: 0.6},
+		models.Bonds:    {ReturnRate: 0.04, Allocation: 0.4},
+	}
+	request.NumSimulations = 10
+
+	response, _ := ForecastFuturePortfolioValueByYear(request)
+	for year, portfolio := range response.Portfolios {
+		expectedVal, _, _ := getNetPortfolioValue(portfolio)
+		band := response.PercentileBands[year]
+		if band.Percentile5 != expectedVal || band.Percentile95 != expectedVal {
+			t.Errorf("expected %v in year %d but got %v", expectedVal, year, band)
+		}
+	}
+}
+
+type PercentileTestCase struct {
+	CaseName   string
+	Values     []models.Money
+	Percentile float64
+	Expected   float64
+}
+
+var percentileCases = []PercentileTestCase{
+	{CaseName: "Empty", Values: []models.Money{}, Percentile: 0.5, Expected: 0},
+	{CaseName: "Single value", Values: []models.Money{models.Dollars(10)}, Percentile: 0.95, Expected: 10},
+	{CaseName: "Exact rank", Values: []models.Money{models.Dollars(10), models.Dollars(20), models.Dollars(30)}, Percentile: 0.5, Expected: 20},
+	{CaseName: "Interpolated rank", Values: []models.Money{0, models.Dollars(100)}, Percentile: 0.25, Expected: 25},
+	{CaseName: "Max", Values: []models.Money{0, models.Dollars(100)}, Percentile: 1.0, Expected: 100},
+}
+
+func TestGetPercentile(t *testing.T) {
+	for _, test := range percentileCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			actual := getPercentile(test.Values, test.Percentile)
+			if actual != models.Dollars(test.Expected) {
+				t.Errorf("expected %v but got %v", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestMonteCarloSuccessProbability(t *testing.T) {
+	response, _ := ForecastFuturePortfolioValueByYear(monteCarloRequest)
+	if response.SuccessProbability != 1.0 {
+		t.Errorf("expected every path to succeed without withdrawals but got %v", response.SuccessProbability)
+	}
+
+	request := monteCarloRequest
+	request.AnnualPortfolioBalanceChanges = []models.AnnualPortfolioBalanceChange{
+		{Amount: models.Dollars(-22_000), StartYear: 1, EndYear: 10},
+	}
+	response, _ = ForecastFuturePortfolioValueByYear(request)
+	if response.SuccessProbability <= 0.0 || response.SuccessProbability >= 1.0 {
+		t.Errorf("expected some but not all paths to deplete but got %v", response.SuccessProbability)
+	}
+}
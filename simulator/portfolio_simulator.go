@@ -1,28 +1,105 @@
 package simulator
 
 import (
-	"errors"
 	"math"
 
 	"github.com/guilam34/financial_planner/models"
 )
 
+// Longer than any household's plan, while keeping a single path from tying up the server
+const maxEndYear = 150
+
 func ForecastFuturePortfolioValueByYear(forecastRequest models.ForecastPortfolioRequest) (models.ForecastPortfolioResponse, error) {
 
-	for _, balanceChange := range forecastRequest.AnnualPortfolioBalanceChanges {
-		if balanceChange.EndYear > forecastRequest.EndYear {
-			return models.ForecastPortfolioResponse{}, errors.New("annual balance change end year must be less than or equal to last year")
-		}
+	forecastRequest, allocationsNormalized := normalizeAllocations(forecastRequest)
+	// Models that need tax tables or historical data are built before failing so their problems are
+	// reported along with the rest of the request's
+	validationErr := validateForecastRequest(forecastRequest)
+	inflationModel, inflationErr := newInflationModel(forecastRequest)
+	taxCalculator, taxErr := newTaxCalculator(forecastRequest)
+	if err := joinValidationErrors(validationErr, inflationErr, taxErr); err != nil {
+		return models.ForecastPortfolioResponse{}, err
+	}
+	strategies := simulationStrategies{
+		rebalancingStrategy:        getRebalancingStrategy(forecastRequest),
+		taxCalculator:              taxCalculator,
+		withdrawalOrderingStrategy: getWithdrawalOrderingStrategy(forecastRequest),
 	}
 
-	allocatedPortfolioPct := 0.0
-	for _, allocation := range forecastRequest.PortfolioAllocation {
-		allocatedPortfolioPct = allocatedPortfolioPct + allocation.Allocation
+	path := simulatePortfolioPath(
+		forecastRequest,
+		strategies,
+		ConstantReturns{
+			portfolioAllocation: forecastRequest.PortfolioAllocation,
+			inflationModel:      inflationModel,
+		})
+	response := models.ForecastPortfolioResponse{
+		Portfolios:                   path.portfolios,
+		AllocationsNormalized:        allocationsNormalized,
+		AccountBalances:              path.accountBalances,
+		TaxesPaid:                    path.taxesPaid,
+		Spending:                     path.spending,
+		FeesPaid:                     path.feesPaid,
+		TotalFeesPaid:                path.totalFeesPaid,
+		RebalanceYears:               path.rebalanceYears,
+		Trades:                       path.trades,
+		Turnover:                     path.turnover,
+		TransactionCosts:             path.transactionCosts,
+		TotalTransactionCosts:        path.totalTransactionCosts,
+		AccountWithdrawals:           path.accountWithdrawals,
+		RequiredMinimumDistributions: path.rmds,
+		SocialSecurityIncome:         path.socialSecurityIncome,
+		GuaranteedIncome:             path.guaranteedIncome,
+		IncomeSourcePayments:         path.incomeSourcePayments,
+		RothConversions:              path.rothConversions,
+		InflationRates:               path.inflationRates,
+		Depleted:                     path.depleted,
+		DepletionYear:                path.depletionYear,
+		TotalShortfall:               path.totalShortfall,
+		SuccessProbability:           1.0,
 	}
-	if allocatedPortfolioPct != 1.0 {
-		return models.ForecastPortfolioResponse{}, errors.New("portfolio allocation percent must sum up to 1")
+	if path.depleted {
+		response.SuccessProbability = 0.0
+	}
+	if len(forecastRequest.RothConversions) > 0 {
+		rothConversionComparison, err := compareRothConversions(forecastRequest, strategies, path)
+		if err != nil {
+			return models.ForecastPortfolioResponse{}, err
+		}
+		response.RothConversionComparison = rothConversionComparison
 	}
 
+	switch forecastRequest.SimulationMode {
+	case models.MonteCarlo:
+		percentileBands, successProbability, err := runMonteCarloSimulation(forecastRequest, strategies)
+		if err != nil {
+			return models.ForecastPortfolioResponse{}, err
+		}
+		response.PercentileBands = percentileBands
+		response.SuccessProbability = successProbability
+		break
+	case models.Historical:
+		historicalPeriods, percentileBands, successProbability, err := runHistoricalBacktest(forecastRequest, strategies)
+		if err != nil {
+			return models.ForecastPortfolioResponse{}, err
+		}
+		response.HistoricalPeriods = historicalPeriods
+		response.PercentileBands = percentileBands
+		response.SuccessProbability = successProbability
+		break
+	case models.Bootstrap:
+		percentileBands, successProbability, err := runBootstrapSimulation(forecastRequest, strategies)
+		if err != nil {
+			return models.ForecastPortfolioResponse{}, err
+		}
+		response.PercentileBands = percentileBands
+		response.SuccessProbability = successProbability
+		break
+	}
+	return response, nil
+}
+
+func getRebalancingStrategy(forecastRequest models.ForecastPortfolioRequest) RebalancingStrategy {
 	var rebalancingStrategy RebalancingStrategy
 	switch forecastRequest.RebalancingStrategy {
 	case models.YearlyToZero:
@@ -31,61 +108,425 @@ func ForecastFuturePortfolioValueByYear(forecastRequest models.ForecastPortfolio
 	case models.EveryNYearsByAlloc:
 		rebalancingStrategy = RebalanceEveryNYears{rebalanceCadence: forecastRequest.RebalanceCadence}
 		break
+	case models.OutsideBandsByAlloc:
+		rebalancingStrategy = RebalanceOutsideBands{
+			absoluteBand: forecastRequest.AbsoluteRebalanceBand,
+			relativeBand: forecastRequest.RelativeRebalanceBand,
+		}
+		break
+	case models.CashFlowByAlloc:
+		rebalancingStrategy = RebalanceWithCashFlows{
+			bands: RebalanceOutsideBands{
+				absoluteBand: forecastRequest.AbsoluteRebalanceBand,
+				relativeBand: forecastRequest.RelativeRebalanceBand,
+			},
+		}
+		break
 	default:
 		rebalancingStrategy = RebalanceToZero{}
 		break
 	}
+	return rebalancingStrategy
+}
+
+// Strategies shared by every simulated path of a forecast
+type simulationStrategies struct {
+	rebalancingStrategy        RebalancingStrategy
+	taxCalculator              TaxCalculator
+	withdrawalOrderingStrategy WithdrawalOrderingStrategy
+}
 
-	result := []models.Portfolio{forecastRequest.InitPortfolio}
-	prevPortfolio := forecastRequest.InitPortfolio
+type portfolioPath struct {
+	// Initial portfolio followed by one household portfolio per year
+	portfolios           []models.Portfolio
+	accountBalances      [][]models.Account
+	taxesPaid            []models.Money
+	accountWithdrawals   [][]models.AccountWithdrawal
+	spending             []models.Money
+	feesPaid             []models.Money
+	rebalanceYears       []int
+	trades               [][]models.Trade
+	turnover             []float64
+	transactionCosts     []models.Money
+	rmds                 []models.Money
+	socialSecurityIncome []models.Money
+	guaranteedIncome     []models.Money
+	incomeSourcePayments [][]models.IncomeSourcePayment
+	rothConversions      []models.Money
+	inflationRates       []float64
+	depleted             bool
+	depletionYear        int
+	totalShortfall       models.Money
+	// Sums of transactionCosts and feesPaid
+	totalTransactionCosts models.Money
+	totalFeesPaid         models.Money
+}
+
+// Outcome of a single simulated year
+type yearForecast struct {
+	accounts             []models.Account
+	taxPaid              models.Money
+	withdrawals          []models.AccountWithdrawal
+	spending             models.Money
+	feesPaid             models.Money
+	rebalanced           bool
+	trades               []models.Trade
+	turnover             float64
+	transactionCost      models.Money
+	rmd                  models.Money
+	socialSecurityIncome models.Money
+	guaranteedIncome     models.Money
+	incomeSourcePayments []models.IncomeSourcePayment
+	rothConversion       models.Money
+	unfundedAmount       models.Money
+	// Part of guaranteedIncome taxed as ordinary income
+	taxableGuaranteedIncome models.Money
+}
+
+// Simulates a single path from the initial accounts to the end year, drawing each year's returns
+// from the given generator
+func simulatePortfolioPath(
+	forecastRequest models.ForecastPortfolioRequest,
+	strategies simulationStrategies,
+	returnGenerator ReturnGenerator) portfolioPath {
+
+	initAccounts := getInitAccounts(forecastRequest)
+	path := portfolioPath{
+		portfolios:           []models.Portfolio{getHouseholdPortfolio(initAccounts)},
+		accountBalances:      [][]models.Account{initAccounts},
+		taxesPaid:            []models.Money{0},
+		accountWithdrawals:   [][]models.AccountWithdrawal{{}},
+		spending:             []models.Money{0},
+		feesPaid:             []models.Money{0},
+		trades:               [][]models.Trade{{}},
+		turnover:             []float64{0.0},
+		transactionCosts:     []models.Money{0},
+		rmds:                 []models.Money{0},
+		socialSecurityIncome: []models.Money{0},
+		guaranteedIncome:     []models.Money{0},
+		incomeSourcePayments: [][]models.IncomeSourcePayment{{}},
+		rothConversions:      []models.Money{0},
+		inflationRates:       []float64{0.0},
+	}
+	withdrawalStrategy := newWithdrawalStrategy(forecastRequest)
+	prevAccounts := initAccounts
 	for year := 1; year <= forecastRequest.EndYear; year++ {
-		curPortfolio := forecastNextYearPortfolio(
-			prevPortfolio,
-			forecastRequest.AnnualPortfolioBalanceChanges,
-			convertToRealRates(forecastRequest.PortfolioAllocation, forecastRequest.AnnualInflationRate),
+		portfolioAllocation, inflationRate := returnGenerator.NextYear(year)
+		path.inflationRates = append(path.inflationRates, inflationRate)
+		prevPortfolioValue, _, _ := getNetPortfolioValue(path.portfolios[year-1])
+		forecast := forecastNextYearAccounts(
+			prevAccounts,
+			forecastRequest,
+			convertToRealRates(applyAllocationSchedule(portfolioAllocation, forecastRequest, year), inflationRate),
+			path.inflationRates,
+			withdrawalStrategy.NextYear(year, max(0, prevPortfolioValue)),
 			year,
-			rebalancingStrategy)
-		result = append(result, curPortfolio)
-		prevPortfolio = curPortfolio
+			strategies)
+
+		// Withdrawals beyond what the accounts hold go unfunded rather than being borrowed
+		if forecast.unfundedAmount > 0 {
+			if !path.depleted {
+				path.depleted = true
+				path.depletionYear = year
+			}
+			path.totalShortfall = path.totalShortfall + forecast.unfundedAmount
+		}
+
+		path.portfolios = append(path.portfolios, getHouseholdPortfolio(forecast.accounts))
+		path.accountBalances = append(path.accountBalances, forecast.accounts)
+		path.taxesPaid = append(path.taxesPaid, forecast.taxPaid)
+		path.accountWithdrawals = append(path.accountWithdrawals, forecast.withdrawals)
+		path.spending = append(path.spending, forecast.spending)
+		path.feesPaid = append(path.feesPaid, forecast.feesPaid)
+		path.totalFeesPaid = path.totalFeesPaid + forecast.feesPaid
+		if forecast.rebalanced {
+			path.rebalanceYears = append(path.rebalanceYears, year)
+		}
+		path.trades = append(path.trades, forecast.trades)
+		path.turnover = append(path.turnover, forecast.turnover)
+		path.transactionCosts = append(path.transactionCosts, forecast.transactionCost)
+		path.totalTransactionCosts = path.totalTransactionCosts + forecast.transactionCost
+		path.rmds = append(path.rmds, forecast.rmd)
+		path.socialSecurityIncome = append(path.socialSecurityIncome, forecast.socialSecurityIncome)
+		path.guaranteedIncome = append(path.guaranteedIncome, forecast.guaranteedIncome)
+		path.incomeSourcePayments = append(path.incomeSourcePayments, forecast.incomeSourcePayments)
+		path.rothConversions = append(path.rothConversions, forecast.rothConversion)
+		prevAccounts = forecast.accounts
 	}
-	return models.ForecastPortfolioResponse{Portfolios: result}, nil
+	return path
+}
+
+func emptyPortfolio(portfolio models.Portfolio) models.Portfolio {
+	emptiedPortfolio := models.Portfolio{}
+	for assetType := range portfolio {
+		emptiedPortfolio[assetType] = 0
+	}
+	return emptiedPortfolio
 }
 
 func convertToRealRates(portfolioAllocation models.PortfolioAllocation, inflationRate float64) models.PortfolioAllocation {
 	portfolioAllocationWithRealRates := models.PortfolioAllocation{}
 	for assetType, allocation := range portfolioAllocation {
-		portfolioAllocationWithRealRates[assetType] = models.AssetAllocation{
-			ReturnRate: allocation.ReturnRate - inflationRate,
-			Allocation: allocation.Allocation,
-		}
+		allocation.ReturnRate = allocation.ReturnRate - inflationRate
+		portfolioAllocationWithRealRates[assetType] = allocation
 	}
 	return portfolioAllocationWithRealRates
 }
 
-func forecastNextYearPortfolio(
-	prevPortfolio models.Portfolio,
-	annualPortfolioBalanceChanges []models.AnnualPortfolioBalanceChange,
-	portfolioAllocationWitRealRates models.PortfolioAllocation,
+// Grows each account and deducts fees over each period of the year, landing the period's installment
+// of the year's cash flows at its cash-flow timing. Contributions are applied before withdrawals so
+// that cash flows landing in the same period net out before any shortfall is counted.
+func forecastNextYearAccounts(
+	prevAccounts []models.Account,
+	forecastRequest models.ForecastPortfolioRequest,
+	portfolioAllocationWithRealRates models.PortfolioAllocation,
+	inflationRates []float64,
+	strategySpending models.Money,
 	year int,
-	rebalancingStrategy RebalancingStrategy) models.Portfolio {
+	strategies simulationStrategies) yearForecast {
 
-	forecastedPortfolio := models.Portfolio{}
+	forecast := yearForecast{accounts: make([]models.Account, 0, len(prevAccounts))}
+	for _, prevAccount := range prevAccounts {
+		forecast.accounts = append(forecast.accounts, copyAccount(prevAccount))
+	}
 
-	for assetType, prevAssetVal := range prevPortfolio {
-		forecastedPortfolio[assetType] = prevAssetVal * (1 + portfolioAllocationWitRealRates[assetType].ReturnRate)
+	balanceChangeAmounts := make([]models.Money, len(forecastRequest.AnnualPortfolioBalanceChanges))
+	for i, balanceChange := range forecastRequest.AnnualPortfolioBalanceChanges {
+		balanceChangeAmounts[i], _ = getBalanceChangeAmount(balanceChange, year)
+		if balanceChange.AccountName == "" && balanceChangeAmounts[i] < 0 {
+			forecast.spending = forecast.spending - balanceChangeAmounts[i]
+		}
+	}
+	forecast.spending = forecast.spending + strategySpending
+	forecast.socialSecurityIncome = getSocialSecurityIncome(forecastRequest, year)
+	forecast.incomeSourcePayments, forecast.taxableGuaranteedIncome = getIncomeSourcePayments(forecastRequest, inflationRates, year)
+	for _, payment := range forecast.incomeSourcePayments {
+		forecast.guaranteedIncome = forecast.guaranteedIncome + payment.Amount
 	}
 
-	for _, contrib := range annualPortfolioBalanceChanges {
-		if year >= contrib.StartYear && year <= contrib.EndYear {
-			amtAdjustedForChangePct := contrib.Amount
-			// Adjust for change in contribution after the first year
-			if year > contrib.StartYear {
-				amtAdjustedForChangePct = contrib.Amount * math.Pow(1+contrib.AnnualPctChange, float64(year-contrib.StartYear-1))
+	periodsPerYear := getPeriodsPerYear(forecastRequest)
+	cashFlowTiming := getCashFlowTiming(periodsPerYear)
+	periodYears := 1.0 / float64(periodsPerYear)
+	allocationBeforeCashFlows := compoundReturnRates(portfolioAllocationWithRealRates, periodYears*cashFlowTiming)
+	allocationAfterCashFlows := compoundReturnRates(portfolioAllocationWithRealRates, periodYears*(1-cashFlowTiming))
+	income := &taxableIncome{taxCalculator: strategies.taxCalculator}
+	for period := 1; period <= periodsPerYear; period++ {
+		for i := range forecast.accounts {
+			forecast.taxPaid = forecast.taxPaid + growAccount(&forecast.accounts[i], allocationBeforeCashFlows, forecastRequest.TaxRates)
+		}
+		forecast.feesPaid = forecast.feesPaid + payFees(forecast.accounts, forecastRequest, portfolioAllocationWithRealRates, periodYears)
+		// Holdings before any of the period's cash flows, for strategies that direct cash flows
+		holdingsBeforeCashFlows := make([]models.Portfolio, len(forecast.accounts))
+		for i := range forecast.accounts {
+			holdingsBeforeCashFlows[i] = copyAccount(forecast.accounts[i]).Holdings
+		}
+
+		forecastPeriodCashFlows(
+			&forecast, prevAccounts, forecastRequest, portfolioAllocationWithRealRates, balanceChangeAmounts,
+			strategySpending, year, period, periodsPerYear, strategies, income)
+
+		if cashFlowStrategy, ok := strategies.rebalancingStrategy.(CashFlowRebalancingStrategy); ok {
+			for i := range forecast.accounts {
+				// Accounts opened during the period start out empty
+				prevHoldings := models.Portfolio{}
+				if i < len(holdingsBeforeCashFlows) {
+					prevHoldings = holdingsBeforeCashFlows[i]
+				}
+				prevValue, _, _ := getNetPortfolioValue(prevHoldings)
+				accountValue, _, _ := getNetPortfolioValue(forecast.accounts[i].Holdings)
+				forecast.accounts[i].Holdings = cashFlowStrategy.DirectCashFlow(
+					prevHoldings, accountValue-prevValue, portfolioAllocationWithRealRates)
 			}
-			for assetType, assetAllocation := range portfolioAllocationWitRealRates {
-				forecastedPortfolio[assetType] = forecastedPortfolio[assetType] + float64(amtAdjustedForChangePct)*assetAllocation.Allocation
+		}
+		if cashFlowTiming < 1 {
+			for i := range forecast.accounts {
+				forecast.taxPaid = forecast.taxPaid + growAccount(&forecast.accounts[i], allocationAfterCashFlows, forecastRequest.TaxRates)
 			}
 		}
 	}
-	return rebalancingStrategy.Rebalance(forecastedPortfolio, portfolioAllocationWitRealRates, year)
+	for _, withdrawal := range forecast.withdrawals {
+		forecast.taxPaid = forecast.taxPaid + withdrawal.TaxPaid
+	}
+
+	accounts := forecast.accounts
+	forecast.trades = []models.Trade{}
+	boughtValue, householdValue := models.Money(0), models.Money(0)
+	for i := range accounts {
+		rebalancedHoldings := strategies.rebalancingStrategy.Rebalance(accounts[i].Holdings, portfolioAllocationWithRealRates, year)
+		trades := getRebalancingTrades(accounts[i].Name, accounts[i].Holdings, rebalancedHoldings)
+		accountValue, _, _ := getNetPortfolioValue(accounts[i].Holdings)
+		householdValue = householdValue + accountValue
+		for _, trade := range trades {
+			boughtValue = boughtValue + max(0, trade.Amount)
+		}
+
+		accounts[i].Holdings = rebalancedHoldings
+		transactionCost := payTransactionCosts(&accounts[i], trades, forecastRequest, portfolioAllocationWithRealRates)
+		forecast.transactionCost = forecast.transactionCost + transactionCost
+		forecast.trades = append(forecast.trades, trades...)
+	}
+	forecast.rebalanced = len(forecast.trades) > 0
+	if householdValue > 0 {
+		forecast.turnover = float64(boughtValue) / float64(householdValue)
+	}
+	return forecast
+}
+
+// Applies the period's installment of the year's contributions, withdrawals and income. Annuities
+// are bought in the first period, while RMDs and Roth conversions are left for the last period once
+// the year's other withdrawals and income are known.
+func forecastPeriodCashFlows(
+	forecast *yearForecast,
+	prevAccounts []models.Account,
+	forecastRequest models.ForecastPortfolioRequest,
+	portfolioAllocationWithRealRates models.PortfolioAllocation,
+	balanceChangeAmounts []models.Money,
+	strategySpending models.Money,
+	year int,
+	period int,
+	periodsPerYear int,
+	strategies simulationStrategies,
+	income *taxableIncome) {
+
+	accounts := forecast.accounts
+	for i, balanceChange := range forecastRequest.AnnualPortfolioBalanceChanges {
+		amount := getInstallment(balanceChangeAmounts[i], periodsPerYear, period)
+		if amount <= 0 {
+			continue
+		}
+		accountIdx := max(findAccount(accounts, balanceChange.AccountName), 0)
+		contributeToAccount(&accounts[accountIdx], amount, portfolioAllocationWithRealRates)
+	}
+
+	householdWithdrawal := models.Money(0)
+	for i, balanceChange := range forecastRequest.AnnualPortfolioBalanceChanges {
+		amount := getInstallment(balanceChangeAmounts[i], periodsPerYear, period)
+		if amount >= 0 {
+			continue
+		}
+		if balanceChange.AccountName == "" {
+			householdWithdrawal = householdWithdrawal - amount
+			continue
+		}
+		accountIdx := findAccount(accounts, balanceChange.AccountName)
+		withdrawal := withdrawFromAccount(
+			&accounts[accountIdx], -amount, unlimitedAmount, portfolioAllocationWithRealRates, income)
+		forecast.withdrawals = recordWithdrawal(forecast.withdrawals, withdrawal)
+		forecast.unfundedAmount = forecast.unfundedAmount - amount - withdrawal.NetAmount
+	}
+
+	householdWithdrawal = householdWithdrawal + getInstallment(strategySpending, periodsPerYear, period)
+	if period == 1 {
+		householdPremiums, premiumWithdrawals, unfundedPremiums := payAnnuityPremiums(
+			accounts, forecastRequest, year, portfolioAllocationWithRealRates, income)
+		householdWithdrawal = householdWithdrawal + householdPremiums
+		for _, withdrawal := range premiumWithdrawals {
+			forecast.withdrawals = recordWithdrawal(forecast.withdrawals, withdrawal)
+		}
+		forecast.unfundedAmount = forecast.unfundedAmount + unfundedPremiums
+	}
+
+	var benefitTax models.Money
+	accounts, benefitTax, householdWithdrawal = receiveIncome(
+		accounts,
+		getInstallment(forecast.socialSecurityIncome, periodsPerYear, period),
+		0,
+		getInstallment(forecast.socialSecurityIncome.MulRate(socialSecurityTaxableFraction), periodsPerYear, period),
+		householdWithdrawal,
+		portfolioAllocationWithRealRates,
+		income)
+	forecast.taxPaid = forecast.taxPaid + benefitTax
+
+	var guaranteedIncomeTax models.Money
+	accounts, guaranteedIncomeTax, householdWithdrawal = receiveIncome(
+		accounts,
+		getInstallment(forecast.guaranteedIncome, periodsPerYear, period),
+		getInstallment(forecast.taxableGuaranteedIncome, periodsPerYear, period),
+		0,
+		householdWithdrawal,
+		portfolioAllocationWithRealRates,
+		income)
+	forecast.taxPaid = forecast.taxPaid + guaranteedIncomeTax
+	forecast.accounts = accounts
+
+	if period == periodsPerYear {
+		// RMD proceeds fund spending first and anything left over is reinvested
+		distributions, distributionProceeds := takeRequiredMinimumDistributions(
+			accounts,
+			getRequiredMinimumDistributions(forecastRequest, prevAccounts, year),
+			forecast.withdrawals,
+			portfolioAllocationWithRealRates,
+			income)
+		for _, distribution := range distributions {
+			forecast.withdrawals = recordWithdrawal(forecast.withdrawals, distribution)
+			forecast.rmd = forecast.rmd + distribution.GrossAmount
+		}
+		spendingFromDistributions := min(distributionProceeds, householdWithdrawal)
+		householdWithdrawal = householdWithdrawal - spendingFromDistributions
+		accounts = reinvestProceeds(
+			accounts, distributionProceeds-spendingFromDistributions, portfolioAllocationWithRealRates)
+		forecast.accounts = accounts
+	}
+
+	unfundedHouseholdWithdrawal, householdWithdrawals := strategies.withdrawalOrderingStrategy.Withdraw(
+		accounts, householdWithdrawal, portfolioAllocationWithRealRates, income)
+	forecast.unfundedAmount = forecast.unfundedAmount + unfundedHouseholdWithdrawal
+	for _, withdrawal := range householdWithdrawals {
+		forecast.withdrawals = recordWithdrawal(forecast.withdrawals, withdrawal)
+	}
+
+	if period < periodsPerYear {
+		return
+	}
+	// Conversions come after spending so bracket based conversions only fill the room it leaves
+	for _, conversion := range forecastRequest.RothConversions {
+		if year < conversion.StartYear || year > conversion.EndYear {
+			continue
+		}
+		convertedAmount, conversionTax, taxWithdrawals := convertToRoth(
+			accounts, conversion, portfolioAllocationWithRealRates, income)
+		forecast.rothConversion = forecast.rothConversion + convertedAmount
+		forecast.taxPaid = forecast.taxPaid + conversionTax
+		for _, withdrawal := range taxWithdrawals {
+			forecast.withdrawals = recordWithdrawal(forecast.withdrawals, withdrawal)
+		}
+	}
+}
+
+// Taxes income received outside of the accounts, with Social Security benefits taxed apart from other
+// ordinary income, and uses what's left to fund the household's
+// withdrawal, reinvesting the rest in a taxable account. Returns the accounts, the tax on the income
+// and the withdrawal still left to fund.
+func receiveIncome(
+	accounts []models.Account,
+	amount models.Money,
+	taxableAmount models.Money,
+	taxableBenefits models.Money,
+	householdWithdrawal models.Money,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) ([]models.Account, models.Money, models.Money) {
+
+	if amount <= 0 {
+		return accounts, 0, householdWithdrawal
+	}
+	tax := income.getMarginalTax(taxableAmount, 0)
+	income.add(taxableAmount, 0)
+	tax = tax + income.getMarginalBenefitTax(taxableBenefits)
+	income.addBenefits(taxableBenefits)
+	netAmount := amount - tax
+	spendingFromIncome := min(netAmount, householdWithdrawal)
+	accounts = reinvestProceeds(accounts, netAmount-spendingFromIncome, portfolioAllocation)
+	return accounts, tax, householdWithdrawal - spendingFromIncome
+}
+
+// Returns the amount of the balance change for the year and whether it applies to the year at all
+func getBalanceChangeAmount(balanceChange models.AnnualPortfolioBalanceChange, year int) (models.Money, bool) {
+	if year < balanceChange.StartYear || year > balanceChange.EndYear {
+		return 0, false
+	}
+	// Adjust for change in contribution after the first year
+	if year > balanceChange.StartYear {
+		return balanceChange.Amount.MulRate(math.Pow(1+balanceChange.AnnualPctChange, float64(year-balanceChange.StartYear-1))), true
+	}
+	return balanceChange.Amount, true
 }
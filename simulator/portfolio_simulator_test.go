@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/guilam34/financial_planner/models"
-	"github.com/guilam34/financial_planner/test_utils"
 )
 
 type PortfolioSimulatorTestCase struct {
@@ -28,13 +27,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 200_000,
+			models.Equities: models.Dollars(200_000),
 		},
 	},
 	{
@@ -50,13 +49,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 214_000,
+			models.Equities: models.Dollars(214_000),
 		},
 	},
 	{
@@ -72,13 +71,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 190_000,
+			models.Equities: models.Dollars(190_000),
 		},
 	},
 	{
@@ -88,13 +87,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.0,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
@@ -107,13 +106,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 264_000,
+			models.Equities: models.Dollars(264_000),
 		},
 	},
 	{
@@ -123,13 +122,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.07,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
@@ -142,13 +141,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 250_000,
+			models.Equities: models.Dollars(250_000),
 		},
 	},
 	{
@@ -158,13 +157,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.0,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
@@ -181,14 +180,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 245_000,
-			models.Cash:     5_000,
+			models.Equities: models.Dollars(245_000),
+			models.Cash:     models.Dollars(5_000),
 		},
 	},
 	{
@@ -198,13 +197,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
@@ -221,14 +220,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 249_000,
-			models.Cash:     5_000,
+			models.Equities: models.Dollars(249_000),
+			models.Cash:     models.Dollars(5_000),
 		},
 	},
 	{
@@ -238,13 +237,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         5,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         5,
 					AnnualPctChange: 0.0,
@@ -261,14 +260,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 454_997,
-			models.Cash:     25_000,
+			models.Equities: models.Dollars(454_997.97),
+			models.Cash:     models.Dollars(25_000),
 		},
 	},
 	{
@@ -278,13 +277,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         5,
 					AnnualPctChange: -0.1,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         5,
 					AnnualPctChange: -0.1,
@@ -301,14 +300,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 413_412,
-			models.Cash:     20_475,
+			models.Equities: models.Dollars(413_412.71),
+			models.Cash:     models.Dollars(20_475.5),
 		},
 	},
 	{
@@ -318,13 +317,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         5,
 					AnnualPctChange: 0.1,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         5,
 					AnnualPctChange: 0.1,
@@ -341,14 +340,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 505_682,
-			models.Cash:     30_525,
+			models.Equities: models.Dollars(505_682.58),
+			models.Cash:     models.Dollars(30_525.5),
 		},
 	},
 	{
@@ -358,13 +357,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: 0.0,
@@ -381,14 +380,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 317_279,
-			models.Cash:     10_000,
+			models.Equities: models.Dollars(317_279.97),
+			models.Cash:     models.Dollars(10_000),
 		},
 	},
 	{
@@ -398,13 +397,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: -0.1,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: -0.1,
@@ -421,14 +420,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 312_504,
-			models.Cash:     9500,
+			models.Equities: models.Dollars(312_504.53),
+			models.Cash:     models.Dollars(9500),
 		},
 	},
 	{
@@ -438,13 +437,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          -10_000,
+					Amount:          models.Dollars(-10_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: -0.1,
 				},
 				{
-					Amount:          -40_000,
+					Amount:          models.Dollars(-40_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: -0.1,
@@ -461,13 +460,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 118_940,
+			models.Equities: models.Dollars(118_940.19),
 			models.Cash:     0,
 		},
 	},
@@ -479,25 +478,25 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.05,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          30_000,
+					Amount:          models.Dollars(30_000),
 					StartYear:       1,
 					EndYear:         2,
 					AnnualPctChange: 0.1,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       1,
 					EndYear:         2,
 					AnnualPctChange: 0.1,
 				},
 				{
-					Amount:          -10_000,
+					Amount:          models.Dollars(-10_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: -0.1,
 				},
 				{
-					Amount:          -35_000,
+					Amount:          models.Dollars(-35_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: -0.1,
@@ -514,14 +513,14 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 273_345,
-			models.Cash:     5450,
+			models.Equities: models.Dollars(273_345.95),
+			models.Cash:     models.Dollars(5450),
 		},
 	},
 	{
@@ -531,13 +530,13 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.02,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         60,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         60,
 					AnnualPctChange: 0.0,
@@ -558,15 +557,15 @@ var simulationSuccessCases = []PortfolioSimulatorTestCase{
 				},
 			},
 			InitPortfolio: models.Portfolio{
-				models.Equities: 200_000,
+				models.Equities: models.Dollars(200_000),
 			},
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 19_470_312,
-			models.Bonds:    456_206,
-			models.Cash:     122_504,
+			models.Equities: models.Dollars(19_470_312.83),
+			models.Bonds:    models.Dollars(456_206.24),
+			models.Cash:     models.Dollars(122_504.51),
 		},
 	},
 }
@@ -578,7 +577,7 @@ func TestForecastFuturePortfolioValueByYearSuccessCases(t *testing.T) {
 			forecastedPortfolioForEndYear := forecastedPortfoliosByYear.Portfolios[len(forecastedPortfoliosByYear.Portfolios)-1]
 			for assetType, expectedVal := range test.EndPortfolio {
 				actualVal, ok := forecastedPortfolioForEndYear[assetType]
-				if !ok || !test_utils.AlmostEqual(actualVal, expectedVal) {
+				if !ok || actualVal != expectedVal {
 					t.Errorf("Expected %v but got %v", test.EndPortfolio, forecastedPortfolioForEndYear)
 					t.FailNow()
 				}
@@ -595,13 +594,13 @@ var simulationErrorCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.0,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         1,
 					AnnualPctChange: 0.0,
@@ -630,13 +629,13 @@ var simulationErrorCases = []PortfolioSimulatorTestCase{
 			AnnualInflationRate: 0.0,
 			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
 				{
-					Amount:          10_000,
+					Amount:          models.Dollars(10_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: 0.0,
 				},
 				{
-					Amount:          40_000,
+					Amount:          models.Dollars(40_000),
 					StartYear:       0,
 					EndYear:         2,
 					AnnualPctChange: 0.0,
@@ -646,7 +645,9 @@ var simulationErrorCases = []PortfolioSimulatorTestCase{
 			RebalanceCadence:    1,
 			RebalancingStrategy: models.YearlyToZero,
 		},
-		ErrorMessage: "annual balance change end year must be less than or equal to last year",
+		ErrorMessage: "annual balance change end year must be less than or equal to last year; " +
+			"annual balance change end year must be less than or equal to last year; " +
+			"portfolio allocation percent must sum up to 1",
 	},
 }
 
@@ -660,3 +661,84 @@ func TestForecastFuturePortfolioValueByYearErrorCases(t *testing.T) {
 		})
 	}
 }
+
+type ShortfallTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	Depleted        bool
+	DepletionYear   int
+	TotalShortfall  float64
+	EndPortfolio    models.Portfolio
+}
+
+var shortfallCases = []ShortfallTestCase{
+	{
+		CaseName: "WithdrawalsFullyFunded",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear: 3,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(-30_000), StartYear: 1, EndYear: 3},
+			},
+			PortfolioAllocation: models.PortfolioAllocation{
+				models.Equities: {Allocation: 1.0},
+			},
+			InitPortfolio: models.Portfolio{
+				models.Equities: models.Dollars(100_000),
+			},
+			RebalancingStrategy: models.YearlyToZero,
+		},
+		Depleted:       false,
+		TotalShortfall: 0,
+		EndPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(10_000),
+		},
+	},
+	{
+		CaseName: "WithdrawalsDepletePortfolio",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear: 5,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(-30_000), StartYear: 1, EndYear: 5},
+			},
+			PortfolioAllocation: models.PortfolioAllocation{
+				models.Equities: {Allocation: 0.5},
+				models.Bonds:    {Allocation: 0.5},
+			},
+			InitPortfolio: models.Portfolio{
+				models.Equities: models.Dollars(50_000),
+				models.Bonds:    models.Dollars(50_000),
+			},
+			RebalancingStrategy: models.YearlyToZero,
+		},
+		Depleted:       true,
+		DepletionYear:  4,
+		TotalShortfall: 50_000,
+		EndPortfolio: models.Portfolio{
+			models.Equities: 0,
+			models.Bonds:    0,
+		},
+	},
+}
+
+func TestForecastFuturePortfolioValueByYearShortfallCases(t *testing.T) {
+	for _, test := range shortfallCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			response, _ := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if response.Depleted != test.Depleted || response.DepletionYear != test.DepletionYear {
+				t.Errorf("expected depletion %v in year %d but got %v in year %d",
+					test.Depleted, test.DepletionYear, response.Depleted, response.DepletionYear)
+			}
+			if response.TotalShortfall != models.Dollars(test.TotalShortfall) {
+				t.Errorf("expected shortfall %v but got %v", test.TotalShortfall, response.TotalShortfall)
+			}
+			endPortfolio := response.Portfolios[len(response.Portfolios)-1]
+			for assetType, expectedVal := range test.EndPortfolio {
+				actualVal, ok := endPortfolio[assetType]
+				if !ok || actualVal != expectedVal {
+					t.Errorf("Expected %v but got %v", test.EndPortfolio, endPortfolio)
+					t.FailNow()
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package simulator
 
-import "github.com/guilam34/financial_planner/models"
+import (
+	"math"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
 
 type RebalancingStrategy interface {
 	Rebalance(
@@ -8,6 +13,15 @@ type RebalancingStrategy interface {
 		portfolioAllocation models.PortfolioAllocation, year int) models.Portfolio
 }
 
+// Implemented by rebalancing strategies that steer the year's net cash flow into or out of an
+// account toward its target allocation before rebalancing
+type CashFlowRebalancingStrategy interface {
+	DirectCashFlow(
+		portfolio models.Portfolio,
+		cashFlow models.Money,
+		portfolioAllocation models.PortfolioAllocation) models.Portfolio
+}
+
 type RebalanceToZero struct{}
 
 func (r RebalanceToZero) Rebalance(
@@ -18,7 +32,7 @@ func (r RebalanceToZero) Rebalance(
 	portfolioValue, positiveValAssetTypes, negativeValAssetTypes := getNetPortfolioValue(portfolio)
 
 	// Only rebalance if we're not in the negative
-	if portfolioValue < 0.0 {
+	if portfolioValue < 0 {
 		return portfolio
 	}
 
@@ -27,12 +41,15 @@ func (r RebalanceToZero) Rebalance(
 		rebalancedPortfolio[key] = value
 	}
 
+	slices.SortFunc(positiveValAssetTypes, models.CompareAssetTypes)
+	equalWeights := make([]float64, len(positiveValAssetTypes))
+	for i := range equalWeights {
+		equalWeights[i] = 1.0 / float64(len(positiveValAssetTypes))
+	}
 	for _, assetType := range negativeValAssetTypes {
-		assetVal := portfolio[assetType]
-		amtToSubtractFromEachPosAsset := assetVal / float64(len(positiveValAssetTypes))
 		rebalancedPortfolio[assetType] = 0
-		for _, assetType := range positiveValAssetTypes {
-			rebalancedPortfolio[assetType] = rebalancedPortfolio[assetType] + amtToSubtractFromEachPosAsset
+		for i, amtToSubtract := range splitAmount(portfolio[assetType], equalWeights) {
+			rebalancedPortfolio[positiveValAssetTypes[i]] = rebalancedPortfolio[positiveValAssetTypes[i]] + amtToSubtract
 		}
 	}
 	return rebalancedPortfolio
@@ -50,16 +67,48 @@ func (r RebalanceEveryNYears) Rebalance(
 	portfolioValue, _, negativeValAssetTypes := getNetPortfolioValue(portfolio)
 
 	// Only rebalance if we're not in the negative
-	if portfolioValue < 0.0 {
+	if portfolioValue < 0 {
 		return portfolio
 	}
 
 	if year%r.rebalanceCadence == 0 {
-		rebalancedPortfolio := models.Portfolio{}
-		for assetType, allocation := range portfolioAllocation {
-			rebalancedPortfolio[assetType] = portfolioValue * allocation.Allocation
+		return rebalanceToAllocation(portfolioValue, portfolioAllocation)
+	}
+
+	if len(negativeValAssetTypes) > 0 {
+		return RebalanceToZero{}.Rebalance(portfolio, portfolioAllocation, year)
+	}
+
+	return portfolio
+}
+
+type RebalanceOutsideBands struct {
+	absoluteBand float64
+	relativeBand float64
+}
+
+func (r RebalanceOutsideBands) Rebalance(
+	portfolio models.Portfolio,
+	portfolioAllocation models.PortfolioAllocation,
+	year int) models.Portfolio {
+
+	portfolioValue, _, negativeValAssetTypes := getNetPortfolioValue(portfolio)
+
+	// Only rebalance if we're not in the negative
+	if portfolioValue <= 0 {
+		return portfolio
+	}
+
+	// Assets held outside of the allocation have a target weight of 0
+	for assetType, assetVal := range portfolio {
+		if r.isOutsideBand(float64(assetVal)/float64(portfolioValue), portfolioAllocation[assetType].Allocation) {
+			return rebalanceToAllocation(portfolioValue, portfolioAllocation)
+		}
+	}
+	for assetType, allocation := range portfolioAllocation {
+		if r.isOutsideBand(float64(portfolio[assetType])/float64(portfolioValue), allocation.Allocation) {
+			return rebalanceToAllocation(portfolioValue, portfolioAllocation)
 		}
-		return rebalancedPortfolio
 	}
 
 	if len(negativeValAssetTypes) > 0 {
@@ -69,13 +118,92 @@ func (r RebalanceEveryNYears) Rebalance(
 	return portfolio
 }
 
+func (r RebalanceOutsideBands) isOutsideBand(weight float64, targetWeight float64) bool {
+	tolerance := math.Inf(1)
+	if r.absoluteBand > 0.0 {
+		tolerance = r.absoluteBand
+	}
+	if r.relativeBand > 0.0 {
+		tolerance = min(tolerance, r.relativeBand*targetWeight)
+	}
+	return math.Abs(weight-targetWeight) > tolerance
+}
+
+// Trades only when drift is outside the bands, or to clear negative assets when no band is set
+type RebalanceWithCashFlows struct {
+	bands RebalanceOutsideBands
+}
+
+func (r RebalanceWithCashFlows) Rebalance(
+	portfolio models.Portfolio,
+	portfolioAllocation models.PortfolioAllocation,
+	year int) models.Portfolio {
+
+	if r.bands.absoluteBand == 0.0 && r.bands.relativeBand == 0.0 {
+		return RebalanceToZero{}.Rebalance(portfolio, portfolioAllocation, year)
+	}
+	return r.bands.Rebalance(portfolio, portfolioAllocation, year)
+}
+
+// Splits a contribution across assets in proportion to how far each is below its target value once
+// the contribution lands, or a withdrawal in proportion to how far each is above it. Those gaps
+// always add up to at least the cash flow so no asset overshoots its target.
+func (r RebalanceWithCashFlows) DirectCashFlow(
+	portfolio models.Portfolio,
+	cashFlow models.Money,
+	portfolioAllocation models.PortfolioAllocation) models.Portfolio {
+
+	portfolioValue, _, _ := getNetPortfolioValue(portfolio)
+	targetValue := portfolioValue + cashFlow
+
+	assetGaps := map[models.AssetType]models.Money{}
+	totalGap := models.Money(0)
+	addAssetGap := func(assetType models.AssetType) {
+		if _, ok := assetGaps[assetType]; ok {
+			return
+		}
+		assetGap := targetValue.MulRate(portfolioAllocation[assetType].Allocation) - portfolio[assetType]
+		if cashFlow < 0 {
+			assetGap = -assetGap
+		}
+		assetGaps[assetType] = max(0, assetGap)
+		totalGap = totalGap + assetGaps[assetType]
+	}
+	for assetType := range portfolio {
+		addAssetGap(assetType)
+	}
+	for assetType := range portfolioAllocation {
+		addAssetGap(assetType)
+	}
+	if cashFlow == 0 || totalGap <= 0 {
+		return portfolio
+	}
+
+	assetTypes := sortedAssetTypes(assetGaps)
+	weights := make([]float64, len(assetTypes))
+	for i, assetType := range assetTypes {
+		weights[i] = float64(assetGaps[assetType]) / float64(totalGap)
+	}
+	directedPortfolio := models.Portfolio{}
+	for i, share := range splitAmount(cashFlow, weights) {
+		directedPortfolio[assetTypes[i]] = portfolio[assetTypes[i]] + share
+	}
+	return directedPortfolio
+}
+
+func rebalanceToAllocation(portfolioValue models.Money, portfolioAllocation models.PortfolioAllocation) models.Portfolio {
+	rebalancedPortfolio := models.Portfolio{}
+	spreadAmountByAllocation(&models.Account{Holdings: rebalancedPortfolio}, portfolioValue, portfolioAllocation)
+	return rebalancedPortfolio
+}
+
 func getNetPortfolioValue(
 	portfolio models.Portfolio) (
-	portfolioValue float64,
+	portfolioValue models.Money,
 	positiveValAssetTypes []models.AssetType,
 	negativeValAssetTypes []models.AssetType) {
 
-	portfolioValue = 0.0
+	portfolioValue = 0
 	positiveValAssetTypes = []models.AssetType{}
 	negativeValAssetTypes = []models.AssetType{}
 	for idx, assetVal := range portfolio {
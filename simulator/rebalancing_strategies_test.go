@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/guilam34/financial_planner/models"
-	"github.com/guilam34/financial_planner/test_utils"
 )
 
 type RebalanceToZeroTestCase struct {
@@ -18,42 +17,42 @@ var rebalanceToZeroCases = []RebalanceToZeroTestCase{
 	{
 		CaseName: "One positive",
 		InitPortfolio: models.Portfolio{
-			models.Equities: 200_000,
+			models.Equities: models.Dollars(200_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 200_000,
+			models.Equities: models.Dollars(200_000),
 		},
 	},
 	{
 		CaseName: "One negative",
 		InitPortfolio: models.Portfolio{
-			models.Equities: -200_000,
+			models.Equities: models.Dollars(-200_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: -200_000,
+			models.Equities: models.Dollars(-200_000),
 		},
 	},
 	{
 		CaseName: "One positive, one negative",
 		InitPortfolio: models.Portfolio{
-			models.Equities: 200_000,
-			models.Bonds:    -10_000,
+			models.Equities: models.Dollars(200_000),
+			models.Bonds:    models.Dollars(-10_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 190_000,
+			models.Equities: models.Dollars(190_000),
 			models.Bonds:    0,
 		},
 	},
 	{
 		CaseName: "Two positive, one negative",
 		InitPortfolio: models.Portfolio{
-			models.Equities: 200_000,
-			models.Cash:     50_000,
-			models.Bonds:    -10_000,
+			models.Equities: models.Dollars(200_000),
+			models.Cash:     models.Dollars(50_000),
+			models.Bonds:    models.Dollars(-10_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 195_000,
-			models.Cash:     45_000,
+			models.Equities: models.Dollars(195_000),
+			models.Cash:     models.Dollars(45_000),
 			models.Bonds:    0,
 		},
 	},
@@ -68,7 +67,7 @@ func TestRebalancetoZeroCases(t *testing.T) {
 				test.Year)
 			for assetType, expectedVal := range test.EndPortfolio {
 				actualVal, ok := actualPortfolio[assetType]
-				if !ok || !test_utils.AlmostEqual(actualVal, expectedVal) {
+				if !ok || actualVal != expectedVal {
 					t.Errorf("Expected %v but got %v", test.EndPortfolio, actualPortfolio)
 					t.FailNow()
 				}
@@ -95,10 +94,10 @@ var rebalanceEveryNYearsCases = []RebalanceEveryNYearsTestCase{
 			models.Equities: models.AssetAllocation{Allocation: 1.0},
 		},
 		InitPortfolio: models.Portfolio{
-			models.Equities: -200_000,
+			models.Equities: models.Dollars(-200_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: -200_000,
+			models.Equities: models.Dollars(-200_000),
 		},
 	},
 	{
@@ -109,10 +108,10 @@ var rebalanceEveryNYearsCases = []RebalanceEveryNYearsTestCase{
 			models.Equities: models.AssetAllocation{Allocation: 1.0},
 		},
 		InitPortfolio: models.Portfolio{
-			models.Equities: 200_000,
+			models.Equities: models.Dollars(200_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 200_000,
+			models.Equities: models.Dollars(200_000),
 		},
 	},
 	{
@@ -124,11 +123,11 @@ var rebalanceEveryNYearsCases = []RebalanceEveryNYearsTestCase{
 			models.Bonds:    models.AssetAllocation{Allocation: 0.3},
 		},
 		InitPortfolio: models.Portfolio{
-			models.Equities: 200_000,
-			models.Bonds:    -10_000,
+			models.Equities: models.Dollars(200_000),
+			models.Bonds:    models.Dollars(-10_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 190_000,
+			models.Equities: models.Dollars(190_000),
 			models.Bonds:    0,
 		},
 	},
@@ -141,12 +140,12 @@ var rebalanceEveryNYearsCases = []RebalanceEveryNYearsTestCase{
 			models.Bonds:    models.AssetAllocation{Allocation: 0.3},
 		},
 		InitPortfolio: models.Portfolio{
-			models.Equities: 200_000,
-			models.Bonds:    -10_000,
+			models.Equities: models.Dollars(200_000),
+			models.Bonds:    models.Dollars(-10_000),
 		},
 		EndPortfolio: models.Portfolio{
-			models.Equities: 133_000,
-			models.Bonds:    57_000,
+			models.Equities: models.Dollars(133_000),
+			models.Bonds:    models.Dollars(57_000),
 		},
 	},
 }
@@ -160,7 +159,7 @@ func TestRebalanceEveryNYearsCases(t *testing.T) {
 				test.Year)
 			for assetType, expectedVal := range test.EndPortfolio {
 				actualVal, ok := actualPortfolio[assetType]
-				if !ok || !test_utils.AlmostEqual(actualVal, expectedVal) {
+				if !ok || actualVal != expectedVal {
 					t.Errorf("Expected %v but got %v", test.EndPortfolio, actualPortfolio)
 					t.FailNow()
 				}
@@ -168,3 +167,232 @@ func TestRebalanceEveryNYearsCases(t *testing.T) {
 		})
 	}
 }
+
+type RebalanceOutsideBandsTestCase struct {
+	CaseName            string
+	AbsoluteBand        float64
+	RelativeBand        float64
+	PortfolioAllocation models.PortfolioAllocation
+	InitPortfolio       models.Portfolio
+	EndPortfolio        models.Portfolio
+}
+
+var rebalanceOutsideBandsCases = []RebalanceOutsideBandsTestCase{
+	{
+		CaseName:     "Within absolute band",
+		AbsoluteBand: 0.05,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: models.AssetAllocation{Allocation: 0.6},
+			models.Bonds:    models.AssetAllocation{Allocation: 0.4},
+		},
+		InitPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(63_000),
+			models.Bonds:    models.Dollars(37_000),
+		},
+		EndPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(63_000),
+			models.Bonds:    models.Dollars(37_000),
+		},
+	},
+	{
+		CaseName:     "Outside absolute band",
+		AbsoluteBand: 0.05,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: models.AssetAllocation{Allocation: 0.6},
+			models.Bonds:    models.AssetAllocation{Allocation: 0.4},
+		},
+		InitPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(66_000),
+			models.Bonds:    models.Dollars(34_000),
+		},
+		EndPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(60_000),
+			models.Bonds:    models.Dollars(40_000),
+		},
+	},
+	{
+		CaseName:     "Outside relative band of small allocation",
+		AbsoluteBand: 0.05,
+		RelativeBand: 0.25,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: models.AssetAllocation{Allocation: 0.9},
+			models.Cash:     models.AssetAllocation{Allocation: 0.1},
+		},
+		InitPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(87_000),
+			models.Cash:     models.Dollars(13_000),
+		},
+		EndPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(90_000),
+			models.Cash:     models.Dollars(10_000),
+		},
+	},
+	{
+		CaseName:     "Asset outside of allocation",
+		AbsoluteBand: 0.05,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: models.AssetAllocation{Allocation: 1.0},
+		},
+		InitPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(90_000),
+			models.Bonds:    models.Dollars(10_000),
+		},
+		EndPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(100_000),
+		},
+	},
+	{
+		CaseName:     "Within band w/ some assets negative",
+		AbsoluteBand: 0.5,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: models.AssetAllocation{Allocation: 0.7},
+			models.Bonds:    models.AssetAllocation{Allocation: 0.3},
+		},
+		InitPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(200_000),
+			models.Bonds:    models.Dollars(-10_000),
+		},
+		EndPortfolio: models.Portfolio{
+			models.Equities: models.Dollars(190_000),
+			models.Bonds:    0,
+		},
+	},
+}
+
+func TestRebalanceOutsideBandsCases(t *testing.T) {
+	for _, test := range rebalanceOutsideBandsCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			actualPortfolio := RebalanceOutsideBands{absoluteBand: test.AbsoluteBand, relativeBand: test.RelativeBand}.Rebalance(
+				test.InitPortfolio,
+				test.PortfolioAllocation,
+				1)
+			for assetType, expectedVal := range test.EndPortfolio {
+				actualVal, ok := actualPortfolio[assetType]
+				if !ok || actualVal != expectedVal {
+					t.Errorf("Expected %v but got %v", test.EndPortfolio, actualPortfolio)
+					t.FailNow()
+				}
+			}
+		})
+	}
+}
+
+func TestForecastReportsRebalanceYears(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 3,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {ReturnRate: 0.5, Allocation: 0.5},
+			models.Bonds:    {Allocation: 0.5},
+		},
+		InitPortfolio:         models.Portfolio{models.Equities: models.Dollars(50_000), models.Bonds: models.Dollars(50_000)},
+		RebalancingStrategy:   models.OutsideBandsByAlloc,
+		AbsoluteRebalanceBand: 0.1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// Equities reach 60% after the first year and drift past the band in the second
+	if len(response.RebalanceYears) != 1 || response.RebalanceYears[0] != 2 {
+		t.Errorf("expected a rebalance in year 2 but got %v", response.RebalanceYears)
+	}
+}
+
+func TestBandRebalancingRequiresBand(t *testing.T) {
+	_, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		PortfolioAllocation: singleAssetAllocation,
+		RebalancingStrategy: models.OutsideBandsByAlloc,
+	})
+	expectedMessage := "band rebalancing requires an absolute or relative band"
+	if err == nil || err.Error() != expectedMessage {
+		t.Errorf("expected %v but got %v", expectedMessage, err)
+	}
+}
+
+type DirectCashFlowTestCase struct {
+	CaseName            string
+	CashFlow            float64
+	PortfolioAllocation models.PortfolioAllocation
+	InitPortfolio       models.Portfolio
+	EndPortfolio        models.Portfolio
+}
+
+var sixtyFortyAllocation = models.PortfolioAllocation{
+	models.Equities: models.AssetAllocation{Allocation: 0.6},
+	models.Bonds:    models.AssetAllocation{Allocation: 0.4},
+}
+
+var directCashFlowCases = []DirectCashFlowTestCase{
+	{
+		CaseName:            "Contribution closes the gap",
+		CashFlow:            20_000,
+		PortfolioAllocation: sixtyFortyAllocation,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(30_000)},
+		EndPortfolio:        models.Portfolio{models.Equities: models.Dollars(72_000), models.Bonds: models.Dollars(48_000)},
+	},
+	{
+		CaseName:            "Contribution buys only the underweight asset",
+		CashFlow:            10_000,
+		PortfolioAllocation: sixtyFortyAllocation,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(30_000)},
+		EndPortfolio:        models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(40_000)},
+	},
+	{
+		CaseName:            "Withdrawal sells only the overweight asset",
+		CashFlow:            -10_000,
+		PortfolioAllocation: sixtyFortyAllocation,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(30_000)},
+		EndPortfolio:        models.Portfolio{models.Equities: models.Dollars(60_000), models.Bonds: models.Dollars(30_000)},
+	},
+	{
+		CaseName: "Withdrawal sells assets outside of allocation first",
+		CashFlow: -5_000,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: models.AssetAllocation{Allocation: 1.0},
+		},
+		InitPortfolio: models.Portfolio{models.Equities: models.Dollars(90_000), models.Cash: models.Dollars(10_000)},
+		EndPortfolio:  models.Portfolio{models.Equities: models.Dollars(90_000), models.Cash: models.Dollars(5_000)},
+	},
+}
+
+func TestDirectCashFlowCases(t *testing.T) {
+	for _, test := range directCashFlowCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			actualPortfolio := RebalanceWithCashFlows{}.DirectCashFlow(
+				test.InitPortfolio,
+				models.Dollars(test.CashFlow),
+				test.PortfolioAllocation)
+			for assetType, expectedVal := range test.EndPortfolio {
+				actualVal, ok := actualPortfolio[assetType]
+				if !ok || actualVal != expectedVal {
+					t.Errorf("Expected %v but got %v", test.EndPortfolio, actualPortfolio)
+					t.FailNow()
+				}
+			}
+		})
+	}
+}
+
+func TestForecastWithCashFlowRebalancing(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear:             1,
+		PortfolioAllocation: sixtyFortyAllocation,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(30_000)},
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(10_000), StartYear: 1, EndYear: 1},
+		},
+		RebalancingStrategy:   models.CashFlowByAlloc,
+		AbsoluteRebalanceBand: 0.05,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expectedPortfolio := models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(40_000)}
+	for assetType, expectedVal := range expectedPortfolio {
+		if response.Portfolios[1][assetType] != expectedVal {
+			t.Errorf("expected %v but got %v", expectedPortfolio, response.Portfolios[1])
+		}
+	}
+	if len(response.RebalanceYears) != 0 {
+		t.Errorf("expected the contribution to avoid trading but got rebalances in %v", response.RebalanceYears)
+	}
+}
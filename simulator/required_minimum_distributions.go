@@ -0,0 +1,123 @@
+package simulator
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+// IRS Uniform Lifetime Table of distribution periods by age, the last row applies to every later age
+//
+//go:embed data/uniform_lifetime_table.csv
+var uniformLifetimeTableCsv string
+
+// Parsed when the package loads, so a malformed embedded table fails at startup instead of partway
+// through a forecast
+var uniformLifetimeTable = mustLoadUniformLifetimeTable()
+
+func mustLoadUniformLifetimeTable() map[int]float64 {
+	distributionPeriods, err := loadUniformLifetimeTable()
+	if err != nil {
+		panic(err)
+	}
+	return distributionPeriods
+}
+
+func loadUniformLifetimeTable() (map[int]float64, error) {
+	records, err := csv.NewReader(strings.NewReader(uniformLifetimeTableCsv)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read uniform lifetime table: %w", err)
+	}
+	distributionPeriods := map[int]float64{}
+	// Skip the header row
+	for _, record := range records[1:] {
+		age, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse uniform lifetime table age: %w", err)
+		}
+		distributionPeriods[age], err = strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse distribution period for %d: %w", age, err)
+		}
+	}
+	return distributionPeriods, nil
+}
+
+// RMD age under SECURE 2.0
+func getRmdAge(birthYear int) int {
+	if birthYear <= 1950 {
+		return 72
+	} else if birthYear <= 1959 {
+		return 73
+	}
+	return 75
+}
+
+func getDistributionPeriod(distributionPeriods map[int]float64, age int) float64 {
+	maxAge := 0
+	for tableAge := range distributionPeriods {
+		maxAge = max(maxAge, tableAge)
+	}
+	return distributionPeriods[min(age, maxAge)]
+}
+
+// Calendar year of the simulated year
+func getCalendarYear(forecastRequest models.ForecastPortfolioRequest, year int) int {
+	return forecastRequest.CurrentYear + year
+}
+
+// Required distributions for each tax-deferred account, based on its value at the end of the
+// previous year. Accounts without a required distribution map to 0.
+func getRequiredMinimumDistributions(
+	forecastRequest models.ForecastPortfolioRequest,
+	prevAccounts []models.Account,
+	year int) []models.Money {
+
+	requiredDistributions := make([]models.Money, len(prevAccounts))
+	if forecastRequest.OwnerBirthYear == 0 {
+		return requiredDistributions
+	}
+	age := getCalendarYear(forecastRequest, year) - forecastRequest.OwnerBirthYear
+	if age < getRmdAge(forecastRequest.OwnerBirthYear) {
+		return requiredDistributions
+	}
+
+	distributionPeriod := getDistributionPeriod(uniformLifetimeTable, age)
+	for i, account := range prevAccounts {
+		if account.AccountType != models.TaxDeferred {
+			continue
+		}
+		accountValue, _, _ := getNetPortfolioValue(account.Holdings)
+		requiredDistributions[i] = max(0, accountValue).MulRate(1 / distributionPeriod)
+	}
+	return requiredDistributions
+}
+
+// Takes whatever part of each account's required distribution hasn't already been withdrawn this
+// year, returning the distributions and the net proceeds available for spending
+func takeRequiredMinimumDistributions(
+	accounts []models.Account,
+	requiredDistributions []models.Money,
+	withdrawalsSoFar []models.AccountWithdrawal,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) (distributions []models.AccountWithdrawal, netProceeds models.Money) {
+
+	for i, requiredDistribution := range requiredDistributions {
+		for _, withdrawal := range withdrawalsSoFar {
+			if withdrawal.AccountName == accounts[i].Name {
+				requiredDistribution = requiredDistribution - withdrawal.GrossAmount
+			}
+		}
+		if requiredDistribution <= 0 {
+			continue
+		}
+		distribution := withdrawFromAccount(&accounts[i], unlimitedAmount, requiredDistribution, portfolioAllocation, income)
+		distributions = append(distributions, distribution)
+		netProceeds = netProceeds + distribution.NetAmount
+	}
+	return distributions, netProceeds
+}
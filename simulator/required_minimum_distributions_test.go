@@ -0,0 +1,126 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+func TestLoadUniformLifetimeTable(t *testing.T) {
+	distributionPeriods, err := loadUniformLifetimeTable()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if distributionPeriods[73] != 26.5 {
+		t.Errorf("expected a distribution period of 26.5 at 73 but got %v", distributionPeriods[73])
+	}
+	if getDistributionPeriod(distributionPeriods, 125) != 2.0 {
+		t.Errorf("expected ages past the table to use its last row but got %v", getDistributionPeriod(distributionPeriods, 125))
+	}
+}
+
+func TestGetRmdAge(t *testing.T) {
+	expectedAges := map[int]int{1949: 72, 1950: 72, 1951: 73, 1959: 73, 1960: 75, 1975: 75}
+	for birthYear, expectedAge := range expectedAges {
+		if actualAge := getRmdAge(birthYear); actualAge != expectedAge {
+			t.Errorf("expected RMD age %d for %d but got %d", expectedAge, birthYear, actualAge)
+		}
+	}
+}
+
+type RmdTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	Rmd             float64
+	EndAccounts     map[string]float64
+}
+
+func newRmdRequest(
+	ownerBirthYear int,
+	balanceChanges []models.AnnualPortfolioBalanceChange,
+	accounts ...models.Account) models.ForecastPortfolioRequest {
+
+	return models.ForecastPortfolioRequest{
+		EndYear:                       1,
+		AnnualPortfolioBalanceChanges: balanceChanges,
+		PortfolioAllocation:           singleAssetAllocation,
+		Accounts:                      accounts,
+		TaxRates:                      models.TaxRates{OrdinaryIncomeRate: 0.2},
+		CurrentYear:                   2023,
+		OwnerBirthYear:                ownerBirthYear,
+	}
+}
+
+var rmdTraditionalIra = models.Account{
+	Name:        "IRA",
+	AccountType: models.TaxDeferred,
+	Holdings:    models.Portfolio{models.Equities: models.Dollars(265_000)},
+}
+
+var rmdBrokerage = models.Account{
+	Name:        "Brokerage",
+	AccountType: models.Taxable,
+	Holdings:    models.Portfolio{models.Equities: models.Dollars(50_000)},
+	CostBasis:   models.Dollars(50_000),
+}
+
+var rmdCases = []RmdTestCase{
+	{
+		CaseName:        "BeforeRmdAge",
+		ForecastRequest: newRmdRequest(1952, nil, rmdTraditionalIra),
+		Rmd:             0,
+		EndAccounts:     map[string]float64{"IRA": 265_000},
+	},
+	{
+		CaseName:        "ExcessReinvestedIntoNewTaxableAccount",
+		ForecastRequest: newRmdRequest(1951, nil, rmdTraditionalIra),
+		Rmd:             10_000,
+		EndAccounts:     map[string]float64{"IRA": 255_000, reinvestmentAccountName: 8_000},
+	},
+	{
+		CaseName:        "ExcessReinvestedIntoExistingTaxableAccount",
+		ForecastRequest: newRmdRequest(1951, nil, rmdTraditionalIra, rmdBrokerage),
+		Rmd:             10_000,
+		EndAccounts:     map[string]float64{"IRA": 255_000, "Brokerage": 58_000},
+	},
+	{
+		CaseName: "RmdFundsSpendingFirst",
+		ForecastRequest: newRmdRequest(1951, []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-30_000), StartYear: 1, EndYear: 1},
+		}, rmdTraditionalIra, rmdBrokerage),
+		Rmd:         10_000,
+		EndAccounts: map[string]float64{"IRA": 255_000, "Brokerage": 28_000},
+	},
+	{
+		CaseName: "WithdrawalsFromAccountCountTowardsRmd",
+		ForecastRequest: newRmdRequest(1951, []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-4_000), StartYear: 1, EndYear: 1, AccountName: "IRA"},
+		}, rmdTraditionalIra, rmdBrokerage),
+		Rmd:         5_000,
+		EndAccounts: map[string]float64{"IRA": 255_000, "Brokerage": 54_000},
+	},
+}
+
+func TestRequiredMinimumDistributions(t *testing.T) {
+	for _, test := range rmdCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			response, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if response.RequiredMinimumDistributions[1] != models.Dollars(test.Rmd) {
+				t.Errorf("expected an RMD of %v but got %v", test.Rmd, response.RequiredMinimumDistributions[1])
+			}
+			endAccounts := response.AccountBalances[1]
+			if len(endAccounts) != len(test.EndAccounts) {
+				t.Fatalf("expected %v but got %v", test.EndAccounts, endAccounts)
+			}
+			for _, account := range endAccounts {
+				expectedVal := test.EndAccounts[account.Name]
+				if actualVal, _, _ := getNetPortfolioValue(account.Holdings); actualVal != models.Dollars(expectedVal) {
+					t.Errorf("expected %v in %s but got %v", expectedVal, account.Name, actualVal)
+				}
+			}
+		})
+	}
+}
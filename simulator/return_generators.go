@@ -0,0 +1,95 @@
+package simulator
+
+import (
+	"math/rand/v2"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Provides the nominal asset returns and inflation rate to apply in a given simulated year
+type ReturnGenerator interface {
+	NextYear(year int) (portfolioAllocation models.PortfolioAllocation, inflationRate float64)
+}
+
+// Applies each asset's expected return every year
+type ConstantReturns struct {
+	portfolioAllocation models.PortfolioAllocation
+	inflationModel      InflationModel
+}
+
+func (c ConstantReturns) NextYear(year int) (models.PortfolioAllocation, float64) {
+	return c.portfolioAllocation, c.inflationModel.NextYear(year, 0.0)
+}
+
+// Jointly draws asset returns from normal distributions centered on their expected rates along
+// with the inflation shock. Independent standard normal draws are correlated through the Cholesky
+// factor of the correlation matrix, whose last row corresponds to inflation.
+type NormalReturns struct {
+	portfolioAllocation models.PortfolioAllocation
+	inflationModel      InflationModel
+	assetTypes          []models.AssetType
+	choleskyFactor      [][]float64
+	rng                 *rand.Rand
+}
+
+func newNormalReturns(forecastRequest models.ForecastPortfolioRequest, rng *rand.Rand) (NormalReturns, error) {
+	assetTypes, correlationMatrix, err := buildCorrelationMatrix(forecastRequest)
+	if err != nil {
+		return NormalReturns{}, err
+	}
+	choleskyFactor, err := choleskyDecomposition(correlationMatrix)
+	if err != nil {
+		return NormalReturns{}, newValidationError("AssetCorrelations", models.InvalidCorrelations, err.Error())
+	}
+	inflationModel, err := newInflationModel(forecastRequest)
+	if err != nil {
+		return NormalReturns{}, err
+	}
+	return NormalReturns{
+		portfolioAllocation: forecastRequest.PortfolioAllocation,
+		inflationModel:      inflationModel,
+		assetTypes:          assetTypes,
+		choleskyFactor:      choleskyFactor,
+		rng:                 rng,
+	}, nil
+}
+
+func (n NormalReturns) NextYear(year int) (models.PortfolioAllocation, float64) {
+	independentDraws := make([]float64, len(n.choleskyFactor))
+	for i := range independentDraws {
+		independentDraws[i] = n.rng.NormFloat64()
+	}
+	correlatedDraws := make([]float64, len(n.choleskyFactor))
+	for i, row := range n.choleskyFactor {
+		for j := 0; j <= i; j++ {
+			correlatedDraws[i] = correlatedDraws[i] + row[j]*independentDraws[j]
+		}
+	}
+
+	sampledAllocation := models.PortfolioAllocation{}
+	for i, assetType := range n.assetTypes {
+		allocation := n.portfolioAllocation[assetType]
+		allocation.ReturnRate = boundReturnRate(allocation.ReturnRate + correlatedDraws[i]*allocation.Volatility)
+		sampledAllocation[assetType] = allocation
+	}
+	return sampledAllocation, n.inflationModel.NextYear(year, correlatedDraws[len(n.assetTypes)])
+}
+
+// An asset can't lose more than its entire value in a single year
+func boundReturnRate(returnRate float64) float64 {
+	if returnRate < -1.0 {
+		return -1.0
+	}
+	return returnRate
+}
+
+// Map iteration order is random so draws need a stable asset order for seeded runs to be reproducible
+func sortedAssetTypes[V any](assets map[models.AssetType]V) []models.AssetType {
+	assetTypes := make([]models.AssetType, 0, len(assets))
+	for assetType := range assets {
+		assetTypes = append(assetTypes, assetType)
+	}
+	slices.SortFunc(assetTypes, models.CompareAssetTypes)
+	return assetTypes
+}
@@ -0,0 +1,99 @@
+package simulator
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+func TestConstantReturns(t *testing.T) {
+	portfolioAllocation := models.PortfolioAllocation{
+		models.Equities: {ReturnRate: 0.07, Allocation: 1.0, Volatility: 0.2},
+	}
+	generator := ConstantReturns{
+		portfolioAllocation: portfolioAllocation,
+		inflationModel:      ConstantInflationModel{inflationRate: 0.03, inflationVolatility: 0.01},
+	}
+	for year := 1; year <= 3; year++ {
+		allocation, inflationRate := generator.NextYear(year)
+		if allocation[models.Equities].ReturnRate != 0.07 || inflationRate != 0.03 {
+			t.Errorf("expected constant returns but got %v and %v", allocation, inflationRate)
+		}
+	}
+}
+
+func TestNormalReturnsAreBoundedAndCenteredOnExpectedReturn(t *testing.T) {
+	forecastRequest := models.ForecastPortfolioRequest{
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {ReturnRate: 0.07, Allocation: 1.0, Volatility: 0.6},
+		},
+	}
+	generator, _ := newNormalReturns(forecastRequest, rand.New(rand.NewPCG(1, 1)))
+
+	numDraws := 20_000
+	total := 0.0
+	for i := 0; i < numDraws; i++ {
+		allocation, _ := generator.NextYear(1)
+		returnRate := allocation[models.Equities].ReturnRate
+		if returnRate < -1.0 {
+			t.Fatalf("expected return rate to be bounded at -1 but got %v", returnRate)
+		}
+		total = total + returnRate
+	}
+
+	mean := total / float64(numDraws)
+	if mean < 0.05 || mean > 0.10 {
+		t.Errorf("expected mean return near 0.07 but got %v", mean)
+	}
+}
+
+func TestNormalReturnsAreCorrelated(t *testing.T) {
+	forecastRequest := models.ForecastPortfolioRequest{
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {ReturnRate: 0.07, Allocation: 0.5, Volatility: 0.2},
+			models.Bonds:    {ReturnRate: 0.03, Allocation: 0.5, Volatility: 0.05},
+		},
+		AnnualInflationRate:       0.03,
+		AnnualInflationVolatility: 0.01,
+		AssetCorrelations: []models.AssetCorrelation{
+			{AssetType: models.Equities, OtherAssetType: models.Bonds, Correlation: -0.6},
+		},
+		InflationCorrelations: map[models.AssetType]float64{
+			models.Bonds: 0.8,
+		},
+	}
+	generator, err := newNormalReturns(forecastRequest, rand.New(rand.NewPCG(7, 7)))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	equityReturns, bondReturns, inflationRates := []float64{}, []float64{}, []float64{}
+	for i := 0; i < 20_000; i++ {
+		allocation, inflationRate := generator.NextYear(1)
+		equityReturns = append(equityReturns, allocation[models.Equities].ReturnRate)
+		bondReturns = append(bondReturns, allocation[models.Bonds].ReturnRate)
+		inflationRates = append(inflationRates, inflationRate)
+	}
+
+	if correlation := getPearsonCorrelation(equityReturns, bondReturns); correlation > -0.55 || correlation < -0.65 {
+		t.Errorf("expected equity/bond correlation near -0.6 but got %v", correlation)
+	}
+	if correlation := getPearsonCorrelation(bondReturns, inflationRates); correlation < 0.75 || correlation > 0.85 {
+		t.Errorf("expected bond/inflation correlation near 0.8 but got %v", correlation)
+	}
+}
+
+func TestSortedAssetTypesPutsPresetsFirst(t *testing.T) {
+	assetTypes := sortedAssetTypes(models.PortfolioAllocation{
+		"REIT":          {Allocation: 0.2},
+		models.Cash:     {Allocation: 0.1},
+		"Commodities":   {Allocation: 0.1},
+		models.Equities: {Allocation: 0.6},
+	})
+	expectedAssetTypes := []models.AssetType{models.Equities, models.Cash, "Commodities", "REIT"}
+	if !slices.Equal(assetTypes, expectedAssetTypes) {
+		t.Errorf("expected %v but got %v", expectedAssetTypes, assetTypes)
+	}
+}
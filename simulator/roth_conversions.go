@@ -0,0 +1,113 @@
+package simulator
+
+import (
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Returns the named account, or the first account of the type when no name is given
+func findRothConversionAccount(
+	accounts []models.Account,
+	accountName string,
+	accountType models.AccountTypeEnum) int {
+
+	if accountName != "" {
+		return findAccount(accounts, accountName)
+	}
+	return slices.IndexFunc(accounts, func(account models.Account) bool {
+		return account.AccountType == accountType
+	})
+}
+
+// Converts the year's amount to the tax-free account, paying the tax from taxable accounts. Tax the
+// taxable accounts can't cover is withheld from the conversion. Returns the amount that reached the
+// tax-free account, the tax on the conversion and the withdrawals that paid it.
+func convertToRoth(
+	accounts []models.Account,
+	conversion models.RothConversion,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) (convertedAmount models.Money, taxPaid models.Money, taxWithdrawals []models.AccountWithdrawal) {
+
+	fromAccount := &accounts[findRothConversionAccount(accounts, conversion.FromAccountName, models.TaxDeferred)]
+	toAccount := &accounts[findRothConversionAccount(accounts, conversion.ToAccountName, models.TaxFree)]
+	fromAccountValue, _, _ := getNetPortfolioValue(fromAccount.Holdings)
+
+	amount := conversion.Amount
+	if conversion.ConversionType == models.FillBracketConversion {
+		amount = income.taxCalculator.GetBracketCeiling(conversion.TargetBracketRate) - income.ordinaryIncome - income.socialSecurityIncome
+	}
+	amount = min(amount, fromAccountValue)
+	if amount <= 0 {
+		return 0, 0, nil
+	}
+
+	taxPaid = income.getMarginalTax(amount, 0)
+	income.add(amount, 0)
+	if amount >= fromAccountValue {
+		fromAccount.Holdings = emptyPortfolio(fromAccount.Holdings)
+	} else {
+		spreadAmountByAllocation(fromAccount, -amount, portfolioAllocation)
+	}
+
+	unpaidTax := withdrawFromAccountTypes(
+		accounts,
+		taxPaid,
+		[]models.AccountTypeEnum{models.Taxable},
+		portfolioAllocation,
+		income,
+		&taxWithdrawals)
+	convertedAmount = amount - unpaidTax
+	contributeToAccount(toAccount, convertedAmount, portfolioAllocation)
+	return convertedAmount, taxPaid, taxWithdrawals
+}
+
+// Values the accounts as if they were all liquidated at once. Health savings accounts are assumed
+// to go to qualified expenses so they are untaxed.
+func getAfterTaxWealth(accounts []models.Account, taxCalculator TaxCalculator) models.Money {
+	wealth, ordinaryIncome, capitalGains := models.Money(0), models.Money(0), models.Money(0)
+	for _, account := range accounts {
+		accountValue, _, _ := getNetPortfolioValue(account.Holdings)
+		wealth = wealth + accountValue
+		switch account.AccountType {
+		case models.Taxable:
+			capitalGains = capitalGains + max(0, accountValue-account.CostBasis)
+			break
+		case models.TaxDeferred:
+			ordinaryIncome = ordinaryIncome + max(0, accountValue)
+			break
+		}
+	}
+	return wealth - taxCalculator.CalculateTax(ordinaryIncome, capitalGains, 0)
+}
+
+// Compares the path with Roth conversions against the same deterministic forecast without them
+func compareRothConversions(
+	forecastRequest models.ForecastPortfolioRequest,
+	strategies simulationStrategies,
+	path portfolioPath) (models.RothConversionComparison, error) {
+
+	baselineRequest := forecastRequest
+	baselineRequest.RothConversions = nil
+	inflationModel, err := newInflationModel(baselineRequest)
+	if err != nil {
+		return models.RothConversionComparison{}, err
+	}
+	baselinePath := simulatePortfolioPath(
+		baselineRequest,
+		strategies,
+		ConstantReturns{
+			portfolioAllocation: baselineRequest.PortfolioAllocation,
+			inflationModel:      inflationModel,
+		})
+
+	comparison := models.RothConversionComparison{
+		AfterTaxWealth:         getAfterTaxWealth(path.accountBalances[len(path.accountBalances)-1], strategies.taxCalculator),
+		BaselineAfterTaxWealth: getAfterTaxWealth(baselinePath.accountBalances[len(baselinePath.accountBalances)-1], strategies.taxCalculator),
+	}
+	for year := range path.taxesPaid {
+		comparison.LifetimeTaxes = comparison.LifetimeTaxes + path.taxesPaid[year]
+		comparison.BaselineLifetimeTaxes = comparison.BaselineLifetimeTaxes + baselinePath.taxesPaid[year]
+	}
+	return comparison, nil
+}
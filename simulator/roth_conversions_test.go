@@ -0,0 +1,182 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type RothConversionTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	ConvertedAmount float64
+	EndAccounts     map[string]float64
+	Comparison      models.RothConversionComparison
+}
+
+func newRothConversionRequest(conversion models.RothConversion, accounts ...models.Account) models.ForecastPortfolioRequest {
+	return models.ForecastPortfolioRequest{
+		EndYear:             1,
+		PortfolioAllocation: singleAssetAllocation,
+		Accounts:            accounts,
+		TaxRates:            models.TaxRates{OrdinaryIncomeRate: 0.2},
+		RothConversions:     []models.RothConversion{conversion},
+	}
+}
+
+var conversionTraditionalIra = models.Account{
+	Name:        "IRA",
+	AccountType: models.TaxDeferred,
+	Holdings:    models.Portfolio{models.Equities: models.Dollars(200_000)},
+}
+
+var conversionRothIra = models.Account{
+	Name:        "Roth",
+	AccountType: models.TaxFree,
+	Holdings:    models.Portfolio{models.Equities: 0},
+}
+
+var conversionBrokerage = models.Account{
+	Name:        "Brokerage",
+	AccountType: models.Taxable,
+	Holdings:    models.Portfolio{models.Equities: models.Dollars(50_000)},
+	CostBasis:   models.Dollars(50_000),
+}
+
+func withBracketTax(forecastRequest models.ForecastPortfolioRequest) models.ForecastPortfolioRequest {
+	forecastRequest.TaxCalculator = models.BracketTax
+	forecastRequest.TaxYear = 2024
+	return forecastRequest
+}
+
+var rothConversionCases = []RothConversionTestCase{
+	{
+		CaseName: "FixedAmountPaidFromTaxable",
+		ForecastRequest: newRothConversionRequest(
+			models.RothConversion{Amount: models.Dollars(10_000), StartYear: 1, EndYear: 1},
+			conversionTraditionalIra, conversionRothIra, conversionBrokerage),
+		ConvertedAmount: 10_000,
+		EndAccounts:     map[string]float64{"IRA": 190_000, "Roth": 10_000, "Brokerage": 48_000},
+		Comparison: models.RothConversionComparison{
+			LifetimeTaxes:          models.Dollars(2_000),
+			BaselineLifetimeTaxes:  0,
+			AfterTaxWealth:         models.Dollars(210_000),
+			BaselineAfterTaxWealth: models.Dollars(210_000),
+		},
+	},
+	{
+		CaseName: "TaxWithheldWithoutTaxableFunds",
+		ForecastRequest: newRothConversionRequest(
+			models.RothConversion{Amount: models.Dollars(10_000), StartYear: 1, EndYear: 1},
+			conversionTraditionalIra, conversionRothIra),
+		ConvertedAmount: 8_000,
+		EndAccounts:     map[string]float64{"IRA": 190_000, "Roth": 8_000},
+		Comparison: models.RothConversionComparison{
+			LifetimeTaxes:          models.Dollars(2_000),
+			BaselineLifetimeTaxes:  0,
+			AfterTaxWealth:         models.Dollars(160_000),
+			BaselineAfterTaxWealth: models.Dollars(160_000),
+		},
+	},
+	{
+		CaseName: "OutsideConversionYears",
+		ForecastRequest: newRothConversionRequest(
+			models.RothConversion{Amount: models.Dollars(10_000), StartYear: 2, EndYear: 1},
+			conversionTraditionalIra, conversionRothIra),
+		ConvertedAmount: 0,
+		EndAccounts:     map[string]float64{"IRA": 200_000, "Roth": 0},
+		Comparison: models.RothConversionComparison{
+			AfterTaxWealth:         models.Dollars(160_000),
+			BaselineAfterTaxWealth: models.Dollars(160_000),
+		},
+	},
+	{
+		CaseName: "FillBracket",
+		ForecastRequest: withBracketTax(newRothConversionRequest(
+			models.RothConversion{
+				ConversionType:    models.FillBracketConversion,
+				TargetBracketRate: 0.12,
+				StartYear:         1,
+				EndYear:           1,
+			},
+			conversionTraditionalIra, conversionRothIra, conversionBrokerage)),
+		// 2024 single 22% bracket threshold plus the standard deduction
+		ConvertedAmount: 61_750,
+		EndAccounts:     map[string]float64{"IRA": 138_250, "Roth": 61_750, "Brokerage": 44_574},
+		Comparison: models.RothConversionComparison{
+			LifetimeTaxes:          models.Dollars(5_426),
+			BaselineLifetimeTaxes:  0,
+			AfterTaxWealth:         models.Dollars(221_855.5),
+			BaselineAfterTaxWealth: models.Dollars(212_461.5),
+		},
+	},
+}
+
+func TestRothConversions(t *testing.T) {
+	for _, test := range rothConversionCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			response, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if response.RothConversions[1] != models.Dollars(test.ConvertedAmount) {
+				t.Errorf("expected %v converted but got %v", test.ConvertedAmount, response.RothConversions[1])
+			}
+			for _, account := range response.AccountBalances[1] {
+				expectedVal := test.EndAccounts[account.Name]
+				if actualVal, _, _ := getNetPortfolioValue(account.Holdings); actualVal != models.Dollars(expectedVal) {
+					t.Errorf("expected %v in %s but got %v", expectedVal, account.Name, actualVal)
+				}
+			}
+			comparison := response.RothConversionComparison
+			if comparison.LifetimeTaxes != test.Comparison.LifetimeTaxes ||
+				comparison.BaselineLifetimeTaxes != test.Comparison.BaselineLifetimeTaxes ||
+				comparison.AfterTaxWealth != test.Comparison.AfterTaxWealth ||
+				comparison.BaselineAfterTaxWealth != test.Comparison.BaselineAfterTaxWealth {
+				t.Errorf("expected %+v but got %+v", test.Comparison, comparison)
+			}
+		})
+	}
+}
+
+var rothConversionErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "ConversionFromTaxableAccount",
+		ForecastRequest: newRothConversionRequest(
+			models.RothConversion{Amount: models.Dollars(10_000), StartYear: 1, EndYear: 1, FromAccountName: "Brokerage"},
+			conversionTraditionalIra, conversionRothIra, conversionBrokerage),
+		ErrorMessage: "roth conversions must convert from a tax-deferred account",
+	},
+	{
+		CaseName: "NoTaxFreeAccount",
+		ForecastRequest: newRothConversionRequest(
+			models.RothConversion{Amount: models.Dollars(10_000), StartYear: 1, EndYear: 1},
+			conversionTraditionalIra, conversionBrokerage),
+		ErrorMessage: "roth conversions must convert into a tax-free account",
+	},
+	{
+		CaseName: "ConversionPastEndYear",
+		ForecastRequest: newRothConversionRequest(
+			models.RothConversion{Amount: models.Dollars(10_000), StartYear: 1, EndYear: 2},
+			conversionTraditionalIra, conversionRothIra),
+		ErrorMessage: "roth conversion end year must be less than or equal to last year",
+	},
+	{
+		CaseName: "NegativeConversionAmount",
+		ForecastRequest: newRothConversionRequest(
+			models.RothConversion{Amount: models.Dollars(-10_000), StartYear: 1, EndYear: 1},
+			conversionTraditionalIra, conversionRothIra),
+		ErrorMessage: "roth conversion amount must be greater than or equal to 0",
+	},
+}
+
+func TestRothConversionErrorCases(t *testing.T) {
+	for _, test := range rothConversionErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package simulator
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const (
+	minClaimingAge = 62
+	maxClaimingAge = 70
+	// Most of the benefits of a retiree with other income are taxable, so the maximum is assumed
+	socialSecurityTaxableFraction = 0.85
+	// Survivors receive at least this fraction of the deceased's primary insurance amount
+	minSurvivorBenefitFraction = 0.825
+	// Simulations shared by every combination of claiming ages when optimizing a stochastic forecast
+	maxClaimingSweepSimulations = 100_000
+)
+
+// Full retirement age in months
+func getFullRetirementAgeMonths(birthYear int) int {
+	if birthYear <= 1937 {
+		return 65 * 12
+	} else if birthYear <= 1942 {
+		return 65*12 + 2*(birthYear-1937)
+	} else if birthYear <= 1954 {
+		return 66 * 12
+	} else if birthYear <= 1959 {
+		return 66*12 + 2*(birthYear-1954)
+	}
+	return 67 * 12
+}
+
+// Fraction of the primary insurance amount paid when claiming at the age, reduced by 5/9 of 1% for
+// each of the first 36 months before full retirement age and 5/12 of 1% for each month beyond, or
+// increased by 2/3 of 1% for each month delayed
+func getClaimingAdjustment(birthYear int, claimingAge int) float64 {
+	monthsFromFullRetirementAge := claimingAge*12 - getFullRetirementAgeMonths(birthYear)
+	if monthsFromFullRetirementAge >= 0 {
+		return 1.0 + float64(monthsFromFullRetirementAge)*2.0/300.0
+	}
+	monthsEarly := -monthsFromFullRetirementAge
+	return 1.0 - float64(min(monthsEarly, 36))*5.0/900.0 - float64(max(0, monthsEarly-36))*5.0/1200.0
+}
+
+// Fraction of the spousal benefit paid when claiming at the age, reduced by 25/36 of 1% for each of
+// the first 36 months before full retirement age and 5/12 of 1% for each month beyond. Delaying
+// doesn't increase spousal benefits.
+func getSpousalAdjustment(birthYear int, claimingAge int) float64 {
+	monthsEarly := max(0, getFullRetirementAgeMonths(birthYear)-claimingAge*12)
+	return 1.0 - float64(min(monthsEarly, 36))*25.0/3600.0 - float64(max(0, monthsEarly-36))*5.0/1200.0
+}
+
+func isAlive(benefit models.SocialSecurityBenefit, calendarYear int) bool {
+	return benefit.DeathAge == 0 || calendarYear-benefit.BirthYear < benefit.DeathAge
+}
+
+func hasClaimed(benefit models.SocialSecurityBenefit, calendarYear int) bool {
+	return calendarYear-benefit.BirthYear >= benefit.ClaimingAge
+}
+
+// Monthly benefit in dollars based on the beneficiary's own record
+func getOwnBenefit(benefit models.SocialSecurityBenefit) float64 {
+	return benefit.PrimaryInsuranceAmount.Dollars() * getClaimingAdjustment(benefit.BirthYear, benefit.ClaimingAge)
+}
+
+// Total benefits the household receives in the year. A spouse receives the larger of their own
+// benefit and, once the other has claimed, half the other's primary insurance amount. Once widowed
+// they receive the larger of their own benefit and the deceased's, as if it had been claimed at the
+// deceased's claiming age.
+func getSocialSecurityIncome(forecastRequest models.ForecastPortfolioRequest, year int) models.Money {
+	calendarYear := getCalendarYear(forecastRequest, year)
+	benefits := forecastRequest.SocialSecurityBenefits
+	income := 0.0
+	for i, benefit := range benefits {
+		if !isAlive(benefit, calendarYear) || !hasClaimed(benefit, calendarYear) {
+			continue
+		}
+		monthlyBenefit := getOwnBenefit(benefit)
+		if len(benefits) == 2 {
+			spouse := benefits[1-i]
+			if !isAlive(spouse, calendarYear) {
+				survivorBenefit := max(getOwnBenefit(spouse), spouse.PrimaryInsuranceAmount.Dollars()*minSurvivorBenefitFraction)
+				monthlyBenefit = max(monthlyBenefit, survivorBenefit)
+			} else if hasClaimed(spouse, calendarYear) {
+				excessSpousalBenefit := max(0.0, spouse.PrimaryInsuranceAmount.Dollars()/2-benefit.PrimaryInsuranceAmount.Dollars())
+				monthlyBenefit = monthlyBenefit + excessSpousalBenefit*getSpousalAdjustment(benefit.BirthYear, benefit.ClaimingAge)
+			}
+		}
+		income = income + 12*monthlyBenefit
+	}
+	return models.Dollars(income)
+}
+
+// Runs the forecast for every combination of claiming ages from 62 to 70 and ranks them
+func OptimizeSocialSecurityClaiming(claimingRequest models.SocialSecurityClaimingRequest) (models.SocialSecurityClaimingResponse, error) {
+	benefits := claimingRequest.ForecastRequest.SocialSecurityBenefits
+	if len(benefits) == 0 {
+		return models.SocialSecurityClaimingResponse{}, newValidationError("ForecastRequest.SocialSecurityBenefits", models.MissingValue, "claiming ages can only be optimized for social security benefits")
+	}
+
+	numClaimingAges := maxClaimingAge - minClaimingAge + 1
+	numCombinations := 1
+	for range benefits {
+		numCombinations = numCombinations * numClaimingAges
+	}
+	baseRequest := claimingRequest.ForecastRequest
+	if baseRequest.SimulationMode == models.MonteCarlo || baseRequest.SimulationMode == models.Bootstrap {
+		maxSweepNumSimulations := maxClaimingSweepSimulations / numCombinations
+		if baseRequest.NumSimulations > maxSweepNumSimulations {
+			return models.SocialSecurityClaimingResponse{}, newValidationError("ForecastRequest.NumSimulations", models.OutOfRange,
+				fmt.Sprintf("number of simulations must be at most %d when optimizing claiming ages", maxSweepNumSimulations))
+		}
+		if baseRequest.NumSimulations == 0 {
+			baseRequest.NumSimulations = min(defaultNumSimulations, maxSweepNumSimulations)
+		}
+	}
+
+	claimingStrategies := make([]models.ClaimingStrategy, 0, numCombinations)
+	for combination := 0; combination < numCombinations; combination++ {
+		forecastRequest := baseRequest
+		forecastRequest.SocialSecurityBenefits = slices.Clone(benefits)
+		claimingAges := make([]int, len(benefits))
+		remainingCombination := combination
+		for i := range benefits {
+			claimingAges[i] = minClaimingAge + remainingCombination%numClaimingAges
+			remainingCombination = remainingCombination / numClaimingAges
+			forecastRequest.SocialSecurityBenefits[i].ClaimingAge = claimingAges[i]
+		}
+
+		forecast, err := ForecastFuturePortfolioValueByYear(forecastRequest)
+		if err != nil {
+			return models.SocialSecurityClaimingResponse{}, prefixFieldErrors(err, "ForecastRequest")
+		}
+		endingWealth, _, _ := getNetPortfolioValue(forecast.Portfolios[len(forecast.Portfolios)-1])
+		claimingStrategies = append(claimingStrategies, models.ClaimingStrategy{
+			ClaimingAges:       claimingAges,
+			EndingWealth:       endingWealth,
+			SuccessProbability: forecast.SuccessProbability,
+		})
+	}
+
+	// Ties on the ranked measure are broken by the other one
+	slices.SortStableFunc(claimingStrategies, func(a models.ClaimingStrategy, b models.ClaimingStrategy) int {
+		primaryA, secondaryA := a.EndingWealth.Dollars(), a.SuccessProbability
+		primaryB, secondaryB := b.EndingWealth.Dollars(), b.SuccessProbability
+		if claimingRequest.RankBy == models.RankBySuccessProbability {
+			primaryA, secondaryA, primaryB, secondaryB = secondaryA, primaryA, secondaryB, primaryB
+		}
+		if primaryA != primaryB {
+			return compareDescending(primaryA, primaryB)
+		}
+		return compareDescending(secondaryA, secondaryB)
+	})
+	return models.SocialSecurityClaimingResponse{ClaimingStrategies: claimingStrategies}, nil
+}
+
+func compareDescending(a float64, b float64) int {
+	if a > b {
+		return -1
+	} else if a < b {
+		return 1
+	}
+	return 0
+}
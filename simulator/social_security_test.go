@@ -0,0 +1,277 @@
+package simulator
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+	"github.com/guilam34/financial_planner/test_utils"
+)
+
+func TestGetFullRetirementAgeMonths(t *testing.T) {
+	expectedMonths := map[int]int{1937: 780, 1940: 786, 1950: 792, 1957: 798, 1962: 804}
+	for birthYear, expected := range expectedMonths {
+		if actual := getFullRetirementAgeMonths(birthYear); actual != expected {
+			t.Errorf("expected %d months for %d but got %d", expected, birthYear, actual)
+		}
+	}
+}
+
+func TestClaimingAdjustments(t *testing.T) {
+	expectedAdjustments := map[int]float64{62: 0.7, 65: 1 - 24*5.0/900, 67: 1.0, 70: 1.24}
+	for claimingAge, expected := range expectedAdjustments {
+		if actual := getClaimingAdjustment(1962, claimingAge); !test_utils.AlmostEqual(actual, expected) {
+			t.Errorf("expected an adjustment of %v at %d but got %v", expected, claimingAge, actual)
+		}
+	}
+	expectedSpousalAdjustments := map[int]float64{62: 0.65, 67: 1.0, 70: 1.0}
+	for claimingAge, expected := range expectedSpousalAdjustments {
+		if actual := getSpousalAdjustment(1962, claimingAge); !test_utils.AlmostEqual(actual, expected) {
+			t.Errorf("expected a spousal adjustment of %v at %d but got %v", expected, claimingAge, actual)
+		}
+	}
+}
+
+type SocialSecurityIncomeTestCase struct {
+	CaseName string
+	Benefits []models.SocialSecurityBenefit
+	Income   float64
+}
+
+// Both spouses are 69 in the year tested
+var socialSecurityIncomeCases = []SocialSecurityIncomeTestCase{
+	{
+		CaseName: "Single",
+		Benefits: []models.SocialSecurityBenefit{{PrimaryInsuranceAmount: models.Dollars(2_000), BirthYear: 1962, ClaimingAge: 67}},
+		Income:   24_000,
+	},
+	{
+		CaseName: "NotYetClaimed",
+		Benefits: []models.SocialSecurityBenefit{{PrimaryInsuranceAmount: models.Dollars(2_000), BirthYear: 1962, ClaimingAge: 70}},
+		Income:   0,
+	},
+	{
+		CaseName: "Spousal",
+		Benefits: []models.SocialSecurityBenefit{
+			{PrimaryInsuranceAmount: models.Dollars(3_000), BirthYear: 1962, ClaimingAge: 67},
+			{PrimaryInsuranceAmount: models.Dollars(1_000), BirthYear: 1962, ClaimingAge: 67},
+		},
+		Income: 36_000 + 18_000,
+	},
+	{
+		CaseName: "SpousalWaitsForOtherToClaim",
+		Benefits: []models.SocialSecurityBenefit{
+			{PrimaryInsuranceAmount: models.Dollars(3_000), BirthYear: 1962, ClaimingAge: 70},
+			{PrimaryInsuranceAmount: models.Dollars(1_000), BirthYear: 1962, ClaimingAge: 67},
+		},
+		Income: 12_000,
+	},
+	{
+		CaseName: "Survivor",
+		Benefits: []models.SocialSecurityBenefit{
+			{PrimaryInsuranceAmount: models.Dollars(3_000), BirthYear: 1962, ClaimingAge: 67, DeathAge: 68},
+			{PrimaryInsuranceAmount: models.Dollars(1_000), BirthYear: 1962, ClaimingAge: 67},
+		},
+		Income: 36_000,
+	},
+	{
+		CaseName: "SurvivorBenefitFloor",
+		Benefits: []models.SocialSecurityBenefit{
+			{PrimaryInsuranceAmount: models.Dollars(3_000), BirthYear: 1962, ClaimingAge: 62, DeathAge: 68},
+			{PrimaryInsuranceAmount: models.Dollars(1_000), BirthYear: 1962, ClaimingAge: 67},
+		},
+		Income: 29_700,
+	},
+}
+
+func TestGetSocialSecurityIncome(t *testing.T) {
+	for _, test := range socialSecurityIncomeCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			forecastRequest := models.ForecastPortfolioRequest{CurrentYear: 2030, SocialSecurityBenefits: test.Benefits}
+			if actual := getSocialSecurityIncome(forecastRequest, 1); actual != models.Dollars(test.Income) {
+				t.Errorf("expected %v but got %v", test.Income, actual)
+			}
+		})
+	}
+}
+
+func TestForecastWithSocialSecurity(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear:             1,
+		PortfolioAllocation: singleAssetAllocation,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-10_000), StartYear: 1, EndYear: 1},
+		},
+		TaxRates:    models.TaxRates{OrdinaryIncomeRate: 0.2},
+		CurrentYear: 2030,
+		SocialSecurityBenefits: []models.SocialSecurityBenefit{
+			{PrimaryInsuranceAmount: models.Dollars(2_000), BirthYear: 1962, ClaimingAge: 67},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if response.SocialSecurityIncome[1] != models.Dollars(24_000) {
+		t.Errorf("expected 24000 of benefits but got %v", response.SocialSecurityIncome[1])
+	}
+	// 85% of the benefits are taxed and whatever spending doesn't use is saved
+	if response.TaxesPaid[1] != models.Dollars(4_080) {
+		t.Errorf("expected 4080 of tax but got %v", response.TaxesPaid[1])
+	}
+	if response.Portfolios[1][models.Equities] != models.Dollars(109_920) {
+		t.Errorf("expected 109920 but got %v", response.Portfolios[1])
+	}
+}
+
+func TestSocialSecuritySurplusIsSavedInTaxableAccount(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear:             1,
+		PortfolioAllocation: singleAssetAllocation,
+		Accounts: []models.Account{
+			{
+				Name:        "IRA",
+				AccountType: models.TaxDeferred,
+				Holdings:    models.Portfolio{models.Equities: models.Dollars(100_000)},
+			},
+		},
+		TaxRates:    models.TaxRates{OrdinaryIncomeRate: 0.2},
+		CurrentYear: 2030,
+		SocialSecurityBenefits: []models.SocialSecurityBenefit{
+			{PrimaryInsuranceAmount: models.Dollars(2_000), BirthYear: 1962, ClaimingAge: 67},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expectedAccounts := map[string]models.Money{
+		"IRA":                   models.Dollars(100_000),
+		reinvestmentAccountName: models.Dollars(19_920),
+	}
+	endAccounts := response.AccountBalances[1]
+	if len(endAccounts) != len(expectedAccounts) {
+		t.Fatalf("expected %v but got %v", expectedAccounts, endAccounts)
+	}
+	for _, account := range endAccounts {
+		if actualVal, _, _ := getNetPortfolioValue(account.Holdings); actualVal != expectedAccounts[account.Name] {
+			t.Errorf("expected %v in %s but got %v", expectedAccounts[account.Name], account.Name, actualVal)
+		}
+	}
+}
+
+func TestOptimizeSocialSecurityClaiming(t *testing.T) {
+	claimingRequest := models.SocialSecurityClaimingRequest{
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             30,
+			PortfolioAllocation: singleAssetAllocation,
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+			CurrentYear:         2024,
+			SocialSecurityBenefits: []models.SocialSecurityBenefit{
+				{PrimaryInsuranceAmount: models.Dollars(2_000), BirthYear: 1962, ClaimingAge: 62},
+				{PrimaryInsuranceAmount: models.Dollars(1_000), BirthYear: 1962, ClaimingAge: 62},
+			},
+		},
+	}
+	response, err := OptimizeSocialSecurityClaiming(claimingRequest)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(response.ClaimingStrategies) != 81 {
+		t.Fatalf("expected 81 claiming strategies but got %d", len(response.ClaimingStrategies))
+	}
+	// Without returns or spending, claiming as late as possible collects the most over 30 years
+	if !slices.Equal(response.ClaimingStrategies[0].ClaimingAges, []int{70, 70}) {
+		t.Errorf("expected claiming at 70 to rank first but got %v", response.ClaimingStrategies[0])
+	}
+	for i := 1; i < len(response.ClaimingStrategies); i++ {
+		if response.ClaimingStrategies[i].EndingWealth > response.ClaimingStrategies[i-1].EndingWealth {
+			t.Fatalf("expected strategies sorted by ending wealth but got %v", response.ClaimingStrategies)
+		}
+	}
+	// The caller's benefits are left untouched
+	if claimingRequest.ForecastRequest.SocialSecurityBenefits[0].ClaimingAge != 62 {
+		t.Errorf("expected the request's claiming ages to be unchanged")
+	}
+}
+
+func TestOptimizeSocialSecurityClaimingCapsSimulations(t *testing.T) {
+	claimingRequest := models.SocialSecurityClaimingRequest{
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             1,
+			PortfolioAllocation: singleAssetAllocation,
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+			CurrentYear:         2024,
+			SimulationMode:      models.MonteCarlo,
+			NumSimulations:      2_000,
+			SocialSecurityBenefits: []models.SocialSecurityBenefit{
+				{PrimaryInsuranceAmount: models.Dollars(2_000), BirthYear: 1962, ClaimingAge: 62},
+				{PrimaryInsuranceAmount: models.Dollars(1_000), BirthYear: 1962, ClaimingAge: 62},
+			},
+		},
+	}
+	// The 81 combinations of two spouses' claiming ages share 100000 simulations
+	_, err := OptimizeSocialSecurityClaiming(claimingRequest)
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.FieldErrors) != 1 ||
+		validationErr.FieldErrors[0].Field != "ForecastRequest.NumSimulations" ||
+		validationErr.FieldErrors[0].Message != "number of simulations must be at most 1234 when optimizing claiming ages" {
+		t.Errorf("expected a simulation limit error but got %v", err)
+	}
+
+	claimingRequest.ForecastRequest.NumSimulations = 0
+	claimingRequest.ForecastRequest.SocialSecurityBenefits = claimingRequest.ForecastRequest.SocialSecurityBenefits[:1]
+	if _, err := OptimizeSocialSecurityClaiming(claimingRequest); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+var socialSecurityErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "ClaimingAgeTooEarly",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation:    singleAssetAllocation,
+			CurrentYear:            2024,
+			SocialSecurityBenefits: []models.SocialSecurityBenefit{{BirthYear: 1962, ClaimingAge: 61}},
+		},
+		ErrorMessage: "social security claiming age must be between 62 and 70",
+	},
+	{
+		CaseName: "NegativePrimaryInsuranceAmount",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation:    singleAssetAllocation,
+			CurrentYear:            2024,
+			SocialSecurityBenefits: []models.SocialSecurityBenefit{{PrimaryInsuranceAmount: models.Dollars(-1), BirthYear: 1962, ClaimingAge: 67}},
+		},
+		ErrorMessage: "primary insurance amount must be greater than or equal to 0",
+	},
+	{
+		CaseName: "BirthYearAfterCurrentYear",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation:    singleAssetAllocation,
+			SocialSecurityBenefits: []models.SocialSecurityBenefit{{BirthYear: 1962, ClaimingAge: 67}},
+		},
+		ErrorMessage: "social security birth year must be less than or equal to the current year",
+	},
+	{
+		CaseName: "MoreThanTwoBenefits",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			SocialSecurityBenefits: []models.SocialSecurityBenefit{
+				{ClaimingAge: 67}, {ClaimingAge: 67}, {ClaimingAge: 67},
+			},
+		},
+		ErrorMessage: "social security benefits are limited to two spouses",
+	},
+}
+
+func TestSocialSecurityErrorCases(t *testing.T) {
+	for _, test := range socialSecurityErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package simulator
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Federal and state income tax brackets keyed by tax year, filing status and two letter state code
+//
+//go:embed data/tax_tables.json
+var taxTablesJson []byte
+
+type taxBracket struct {
+	// Taxable income above which the rate applies
+	Threshold float64
+	Rate      float64
+}
+
+type taxSchedule struct {
+	StandardDeduction float64
+	OrdinaryBrackets  []taxBracket
+	// Long-term capital gains brackets, gains are taxed as ordinary income when empty
+	CapitalGainsBrackets []taxBracket
+}
+
+type taxTable struct {
+	Federal map[string]taxSchedule
+	States  map[string]map[string]taxSchedule
+}
+
+var filingStatusNames = map[models.FilingStatusEnum]string{
+	models.Single:               "Single",
+	models.MarriedFilingJointly: "MarriedFilingJointly",
+}
+
+var loadTaxTables = sync.OnceValues(func() (map[int]taxTable, error) {
+	var rawTaxTables map[string]taxTable
+	if err := json.Unmarshal(taxTablesJson, &rawTaxTables); err != nil {
+		return nil, fmt.Errorf("decode tax tables: %w", err)
+	}
+	taxTables := map[int]taxTable{}
+	for rawYear, table := range rawTaxTables {
+		year, err := strconv.Atoi(rawYear)
+		if err != nil {
+			return nil, fmt.Errorf("parse tax table year: %w", err)
+		}
+		taxTables[year] = table
+	}
+	return taxTables, nil
+})
+
+// Computes the total tax owed on a year's ordinary income, long-term capital gains and taxable Social
+// Security benefits, rounded to the cent
+type TaxCalculator interface {
+	CalculateTax(ordinaryIncome models.Money, capitalGains models.Money, socialSecurityIncome models.Money) models.Money
+	// Ordinary income, before deductions, above which income is taxed at more than the given rate
+	GetBracketCeiling(rate float64) models.Money
+}
+
+type FlatTaxCalculator struct {
+	taxRates models.TaxRates
+}
+
+func (f FlatTaxCalculator) CalculateTax(ordinaryIncome models.Money, capitalGains models.Money, socialSecurityIncome models.Money) models.Money {
+	return models.Dollars((ordinaryIncome+socialSecurityIncome).Dollars()*f.taxRates.OrdinaryIncomeRate +
+		capitalGains.Dollars()*f.taxRates.CapitalGainsRate)
+}
+
+func (f FlatTaxCalculator) GetBracketCeiling(rate float64) models.Money {
+	if rate >= f.taxRates.OrdinaryIncomeRate {
+		return unlimitedAmount
+	}
+	return 0
+}
+
+// Applies progressive federal brackets, with capital gains stacked on top of ordinary income, plus
+// the state's brackets when a state schedule is set
+type BracketTaxCalculator struct {
+	federalSchedule taxSchedule
+	stateSchedule   *taxSchedule
+}
+
+// Brackets are applied in dollars and the total is rounded once. Social Security benefits are taxed
+// as ordinary income federally, while every state with a table exempts them.
+func (b BracketTaxCalculator) CalculateTax(ordinaryIncome models.Money, capitalGains models.Money, socialSecurityIncome models.Money) models.Money {
+	tax := calculateScheduleTax(b.federalSchedule, (ordinaryIncome + socialSecurityIncome).Dollars(), capitalGains.Dollars())
+	if b.stateSchedule != nil {
+		tax = tax + calculateScheduleTax(*b.stateSchedule, ordinaryIncome.Dollars(), capitalGains.Dollars())
+	}
+	return models.Dollars(tax)
+}
+
+// Only federal brackets are considered since state brackets rarely line up with them
+func (b BracketTaxCalculator) GetBracketCeiling(rate float64) models.Money {
+	for _, bracket := range b.federalSchedule.OrdinaryBrackets {
+		if bracket.Rate > rate {
+			return models.Dollars(bracket.Threshold + b.federalSchedule.StandardDeduction)
+		}
+	}
+	return unlimitedAmount
+}
+
+func calculateScheduleTax(schedule taxSchedule, ordinaryIncome float64, capitalGains float64) float64 {
+	if len(schedule.CapitalGainsBrackets) == 0 {
+		taxableIncome := max(0.0, ordinaryIncome+capitalGains-schedule.StandardDeduction)
+		return calculateBracketTax(schedule.OrdinaryBrackets, 0.0, taxableIncome)
+	}
+
+	// The standard deduction offsets ordinary income before capital gains
+	taxableOrdinaryIncome := max(0.0, ordinaryIncome-schedule.StandardDeduction)
+	remainingDeduction := max(0.0, schedule.StandardDeduction-ordinaryIncome)
+	taxableCapitalGains := max(0.0, capitalGains-remainingDeduction)
+
+	return calculateBracketTax(schedule.OrdinaryBrackets, 0.0, taxableOrdinaryIncome) +
+		calculateBracketTax(schedule.CapitalGainsBrackets, taxableOrdinaryIncome, taxableOrdinaryIncome+taxableCapitalGains)
+}
+
+// Taxes the slice of income between incomeStart and incomeEnd at the rates of the brackets it spans
+func calculateBracketTax(brackets []taxBracket, incomeStart float64, incomeEnd float64) float64 {
+	tax := 0.0
+	for i, bracket := range brackets {
+		bracketEnd := incomeEnd
+		if i+1 < len(brackets) {
+			bracketEnd = min(incomeEnd, brackets[i+1].Threshold)
+		}
+		taxedAmount := bracketEnd - max(incomeStart, bracket.Threshold)
+		if taxedAmount > 0.0 {
+			tax = tax + taxedAmount*bracket.Rate
+		}
+	}
+	return tax
+}
+
+// Finds the schedule from the latest tax table at or before the tax year that has one
+func findTaxSchedule(
+	taxTables map[int]taxTable,
+	taxYear int,
+	getSchedule func(taxTable) (taxSchedule, bool)) (taxSchedule, bool) {
+
+	years := make([]int, 0, len(taxTables))
+	for year := range taxTables {
+		years = append(years, year)
+	}
+	slices.Sort(years)
+	slices.Reverse(years)
+	for _, year := range years {
+		if year > taxYear {
+			continue
+		}
+		if schedule, ok := getSchedule(taxTables[year]); ok {
+			return schedule, true
+		}
+	}
+	return taxSchedule{}, false
+}
+
+func newTaxCalculator(forecastRequest models.ForecastPortfolioRequest) (TaxCalculator, error) {
+	if forecastRequest.TaxCalculator != models.BracketTax {
+		return FlatTaxCalculator{taxRates: forecastRequest.TaxRates}, nil
+	}
+
+	taxTables, err := loadTaxTables()
+	if err != nil {
+		return nil, err
+	}
+	taxYear := forecastRequest.TaxYear
+	if taxYear == 0 {
+		for year := range taxTables {
+			taxYear = max(taxYear, year)
+		}
+	}
+	filingStatus, ok := filingStatusNames[forecastRequest.FilingStatus]
+	if !ok {
+		return nil, newValidationError("FilingStatus", models.OutOfRange, "filing status must be single or married filing jointly")
+	}
+
+	federalSchedule, ok := findTaxSchedule(taxTables, taxYear, func(table taxTable) (taxSchedule, bool) {
+		schedule, ok := table.Federal[filingStatus]
+		return schedule, ok
+	})
+	if !ok {
+		return nil, newValidationError("TaxYear", models.UnavailableData, "no federal tax table is available for the tax year")
+	}
+	taxCalculator := BracketTaxCalculator{federalSchedule: federalSchedule}
+
+	if forecastRequest.State != "" {
+		stateSchedule, ok := findTaxSchedule(taxTables, taxYear, func(table taxTable) (taxSchedule, bool) {
+			schedule, ok := table.States[forecastRequest.State][filingStatus]
+			return schedule, ok
+		})
+		if !ok {
+			return nil, newValidationError("State", models.UnavailableData, "no state tax table is available for the state and tax year")
+		}
+		taxCalculator.stateSchedule = &stateSchedule
+	}
+	return taxCalculator, nil
+}
+
+// Tracks the income realized so far in a simulated year so each withdrawal is taxed at the
+// household's marginal rates
+type taxableIncome struct {
+	taxCalculator  TaxCalculator
+	ordinaryIncome models.Money
+	capitalGains   models.Money
+	// Taxable part of the Social Security benefits received
+	socialSecurityIncome models.Money
+}
+
+func (t *taxableIncome) getMarginalTax(ordinaryIncome models.Money, capitalGains models.Money) models.Money {
+	return t.taxCalculator.CalculateTax(t.ordinaryIncome+ordinaryIncome, t.capitalGains+capitalGains, t.socialSecurityIncome) -
+		t.taxCalculator.CalculateTax(t.ordinaryIncome, t.capitalGains, t.socialSecurityIncome)
+}
+
+func (t *taxableIncome) getMarginalBenefitTax(socialSecurityIncome models.Money) models.Money {
+	return t.taxCalculator.CalculateTax(t.ordinaryIncome, t.capitalGains, t.socialSecurityIncome+socialSecurityIncome) -
+		t.taxCalculator.CalculateTax(t.ordinaryIncome, t.capitalGains, t.socialSecurityIncome)
+}
+
+func (t *taxableIncome) add(ordinaryIncome models.Money, capitalGains models.Money) {
+	t.ordinaryIncome = t.ordinaryIncome + ordinaryIncome
+	t.capitalGains = t.capitalGains + capitalGains
+}
+
+func (t *taxableIncome) addBenefits(socialSecurityIncome models.Money) {
+	t.socialSecurityIncome = t.socialSecurityIncome + socialSecurityIncome
+}
@@ -0,0 +1,158 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type TaxCalculatorTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	OrdinaryIncome  float64
+	CapitalGains    float64
+	// Taxable part of Social Security benefits
+	SocialSecurityIncome float64
+	Tax                  float64
+}
+
+var taxCalculatorCases = []TaxCalculatorTestCase{
+	{
+		CaseName:        "Flat",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxRates: models.TaxRates{OrdinaryIncomeRate: 0.2, CapitalGainsRate: 0.1}},
+		OrdinaryIncome:  50_000,
+		CapitalGains:    10_000,
+		Tax:             11_000,
+	},
+	{
+		CaseName:        "FederalOrdinaryIncomeBelowStandardDeduction",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2024},
+		OrdinaryIncome:  14_000,
+		Tax:             0,
+	},
+	{
+		CaseName:        "FederalOrdinaryIncomeAcrossBrackets",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2024},
+		OrdinaryIncome:  100_000,
+		Tax:             13_841,
+	},
+	{
+		CaseName: "FederalMarriedFilingJointly",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			TaxCalculator: models.BracketTax,
+			TaxYear:       2024,
+			FilingStatus:  models.MarriedFilingJointly,
+		},
+		OrdinaryIncome: 100_000,
+		Tax:            8_032,
+	},
+	{
+		CaseName:        "FederalCapitalGainsWithinZeroPercentBracket",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2024},
+		CapitalGains:    60_000,
+		Tax:             0,
+	},
+	{
+		CaseName:        "FederalCapitalGainsStackedOnOrdinaryIncome",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2024},
+		OrdinaryIncome:  40_000,
+		CapitalGains:    40_000,
+		// 25400 of ordinary income is taxed at 10/12%, 21625 of gains at 0% and the rest at 15%
+		Tax: 2_816 + 0.15*18_375,
+	},
+	{
+		CaseName:        "FederalAndState",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2024, State: "IL"},
+		OrdinaryIncome:  50_000,
+		Tax:             4_016 + 2_337.64,
+	},
+	{
+		CaseName:             "StateExemptsSocialSecurity",
+		ForecastRequest:      models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2024, State: "IL"},
+		OrdinaryIncome:       10_000,
+		SocialSecurityIncome: 40_000,
+		// Federal tax is the same as on 50000 of ordinary income while Illinois only taxes the 10000
+		Tax: 4_016 + (10_000-2_775)*0.0495,
+	},
+	{
+		CaseName:        "StateFallsBackToEarlierTable",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2025, State: "CA"},
+		OrdinaryIncome:  20_000,
+		// Federal uses 2025 brackets while California only has a 2024 table
+		Tax: 425 + 107.56 + (14_460-10_756)*0.02,
+	},
+}
+
+func TestTaxCalculators(t *testing.T) {
+	for _, test := range taxCalculatorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			taxCalculator, err := newTaxCalculator(test.ForecastRequest)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			actualTax := taxCalculator.CalculateTax(
+				models.Dollars(test.OrdinaryIncome), models.Dollars(test.CapitalGains), models.Dollars(test.SocialSecurityIncome))
+			if actualTax != models.Dollars(test.Tax) {
+				t.Errorf("expected %v but got %v", test.Tax, actualTax)
+			}
+		})
+	}
+}
+
+var taxCalculatorErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName:        "NoFederalTable",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, TaxYear: 2000},
+		ErrorMessage:    "no federal tax table is available for the tax year",
+	},
+	{
+		CaseName:        "NoStateTable",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, State: "ZZ"},
+		ErrorMessage:    "no state tax table is available for the state and tax year",
+	},
+	{
+		CaseName:        "UnknownFilingStatus",
+		ForecastRequest: models.ForecastPortfolioRequest{TaxCalculator: models.BracketTax, FilingStatus: 7},
+		ErrorMessage:    "filing status must be single or married filing jointly",
+	},
+}
+
+func TestTaxCalculatorErrorCases(t *testing.T) {
+	for _, test := range taxCalculatorErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := newTaxCalculator(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
+
+func TestForecastGrossesUpWithdrawalsWithBrackets(t *testing.T) {
+	forecastRequest := models.ForecastPortfolioRequest{
+		EndYear: 1,
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-50_000), StartYear: 1, EndYear: 1},
+		},
+		PortfolioAllocation: singleAssetAllocation,
+		Accounts: []models.Account{
+			{Name: "401k", AccountType: models.TaxDeferred, Holdings: models.Portfolio{models.Equities: models.Dollars(500_000)}},
+		},
+		TaxCalculator: models.BracketTax,
+		TaxYear:       2025,
+	}
+	response, err := ForecastFuturePortfolioValueByYear(forecastRequest)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	grossWithdrawal := models.Dollars(500_000) - response.Portfolios[1][models.Equities]
+	taxCalculator, _ := newTaxCalculator(forecastRequest)
+	expectedTax := taxCalculator.CalculateTax(grossWithdrawal, 0, 0)
+	if response.TaxesPaid[1] != expectedTax {
+		t.Errorf("expected %v of tax but got %v", expectedTax, response.TaxesPaid[1])
+	}
+	if grossWithdrawal-response.TaxesPaid[1] != models.Dollars(50_000) {
+		t.Errorf("expected 50000 net of tax but got %v", grossWithdrawal-response.TaxesPaid[1])
+	}
+}
@@ -0,0 +1,53 @@
+package simulator
+
+import (
+	"math"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+func getPeriodsPerYear(forecastRequest models.ForecastPortfolioRequest) int {
+	switch forecastRequest.TimeStep {
+	case models.MonthlyTimeStep:
+		return 12
+	case models.QuarterlyTimeStep:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// Fraction of each period that passes before its cash flows land. Annual steps keep their cash flows
+// at the end of the year's growth, while shorter steps land them at the middle of each period.
+func getCashFlowTiming(periodsPerYear int) float64 {
+	if periodsPerYear == 1 {
+		return 1.0
+	}
+	return 0.5
+}
+
+// Compounds each asset's annual return rate over the fraction of a year
+func compoundReturnRates(portfolioAllocation models.PortfolioAllocation, years float64) models.PortfolioAllocation {
+	if years == 1.0 {
+		return portfolioAllocation
+	}
+	compoundedAllocation := models.PortfolioAllocation{}
+	for assetType, allocation := range portfolioAllocation {
+		allocation.ReturnRate = math.Pow(1+allocation.ReturnRate, years) - 1
+		compoundedAllocation[assetType] = allocation
+	}
+	return compoundedAllocation
+}
+
+// Period's equal share of the year's amount. Cents that don't divide evenly go to the earliest
+// periods so the installments add up to exactly the amount.
+func getInstallment(amount models.Money, periodsPerYear int, period int) models.Money {
+	installment := amount / models.Money(periodsPerYear)
+	remainder := amount % models.Money(periodsPerYear)
+	if remainder > 0 && models.Money(period) <= remainder {
+		installment = installment + 1
+	} else if remainder < 0 && models.Money(period) <= -remainder {
+		installment = installment - 1
+	}
+	return installment
+}
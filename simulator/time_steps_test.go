@@ -0,0 +1,169 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type TimeStepTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	EndValue        float64
+}
+
+var growingAllocation = models.PortfolioAllocation{
+	models.Equities: {ReturnRate: 0.12, Allocation: 1.0},
+}
+
+var timeStepCases = []TimeStepTestCase{
+	{
+		CaseName: "AnnualContributionLandsAfterGrowth",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear: 1,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(12_000), StartYear: 1, EndYear: 1},
+			},
+			PortfolioAllocation: growingAllocation,
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+		},
+		EndValue: 124_000,
+	},
+	{
+		CaseName: "MonthlyCompoundsToTheAnnualRate",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             1,
+			TimeStep:            models.MonthlyTimeStep,
+			PortfolioAllocation: growingAllocation,
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+		},
+		EndValue: 112_000,
+	},
+	{
+		CaseName: "MonthlyContributionsLandMidMonth",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:  1,
+			TimeStep: models.MonthlyTimeStep,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(12_000), StartYear: 1, EndYear: 1},
+			},
+			PortfolioAllocation: growingAllocation,
+		},
+		EndValue: 12_706.34,
+	},
+	{
+		CaseName: "QuarterlyContributionsLandMidQuarter",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:  1,
+			TimeStep: models.QuarterlyTimeStep,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(12_000), StartYear: 1, EndYear: 1},
+			},
+			PortfolioAllocation: growingAllocation,
+		},
+		EndValue: 12_705.98,
+	},
+	{
+		CaseName: "MonthlySpendingLandsMidMonth",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:  1,
+			TimeStep: models.MonthlyTimeStep,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(-12_000), StartYear: 1, EndYear: 1},
+			},
+			PortfolioAllocation: growingAllocation,
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+		},
+		EndValue: 112_000 - 12_706.36,
+	},
+	{
+		CaseName: "MonthlyFeesAccrueOnTheFallingBalance",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             1,
+			TimeStep:            models.MonthlyTimeStep,
+			PortfolioAllocation: singleAssetAllocation,
+			InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+			AdvisoryFeeRate:     0.01,
+		},
+		EndValue: 99_004.57,
+	},
+}
+
+func TestTimeStepCases(t *testing.T) {
+	for _, test := range timeStepCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			response, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			endValue, _, _ := getNetPortfolioValue(response.Portfolios[len(response.Portfolios)-1])
+			if endValue != models.Dollars(test.EndValue) {
+				t.Errorf("expected %v but got %v", test.EndValue, endValue)
+			}
+		})
+	}
+}
+
+func TestMonthlyResultsRollUpByYear(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear:  2,
+		TimeStep: models.MonthlyTimeStep,
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-10_000.01), StartYear: 1, EndYear: 2},
+		},
+		PortfolioAllocation: singleAssetAllocation,
+		Accounts: []models.Account{
+			{Name: "401k", AccountType: models.TaxDeferred, Holdings: models.Portfolio{models.Equities: models.Dollars(100_000)}},
+		},
+		TaxRates: models.TaxRates{OrdinaryIncomeRate: 0.2},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(response.Portfolios) != 3 || len(response.Spending) != 3 || len(response.AccountWithdrawals) != 3 {
+		t.Fatalf("expected a row for each year but got %v", response)
+	}
+	withdrawal := response.AccountWithdrawals[1][0]
+	if response.Spending[1] != models.Dollars(10_000.01) || withdrawal.NetAmount != models.Dollars(10_000.01) {
+		t.Errorf("expected 10000.01 spent from the 401k but got %v spending and %v", response.Spending[1], withdrawal)
+	}
+	// Each month's withdrawal is grossed up to the cent, which adds a cent over the year
+	if withdrawal.GrossAmount != models.Dollars(12_500.01) || response.TaxesPaid[1] != withdrawal.TaxPaid {
+		t.Errorf("expected 12500.01 grossed up for tax but got %v with %v taxes", withdrawal, response.TaxesPaid[1])
+	}
+}
+
+type InstallmentTestCase struct {
+	CaseName       string
+	Amount         models.Money
+	PeriodsPerYear int
+	Installments   []models.Money
+}
+
+var installmentCases = []InstallmentTestCase{
+	{CaseName: "Annual", Amount: models.Dollars(100.01), PeriodsPerYear: 1, Installments: []models.Money{models.Dollars(100.01)}},
+	{
+		CaseName:       "LeftoverCentsGoFirst",
+		Amount:         models.Dollars(100.02),
+		PeriodsPerYear: 4,
+		Installments:   []models.Money{models.Dollars(25.01), models.Dollars(25.01), models.Dollars(25), models.Dollars(25)},
+	},
+	{
+		CaseName:       "Negative",
+		Amount:         models.Dollars(-100.01),
+		PeriodsPerYear: 4,
+		Installments:   []models.Money{models.Dollars(-25.01), models.Dollars(-25), models.Dollars(-25), models.Dollars(-25)},
+	},
+}
+
+func TestGetInstallment(t *testing.T) {
+	for _, test := range installmentCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			for i, expected := range test.Installments {
+				if actual := getInstallment(test.Amount, test.PeriodsPerYear, i+1); actual != expected {
+					t.Errorf("expected %v in period %d but got %v", expected, i+1, actual)
+				}
+			}
+		})
+	}
+}
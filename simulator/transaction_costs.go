@@ -0,0 +1,54 @@
+package simulator
+
+import (
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const basisPoint = 0.0001
+
+// Trades that take the portfolio to the rebalanced portfolio, ordered by asset type
+func getRebalancingTrades(
+	accountName string,
+	portfolio models.Portfolio,
+	rebalancedPortfolio models.Portfolio) []models.Trade {
+
+	assetTypes := []models.AssetType{}
+	for _, holdings := range []models.Portfolio{portfolio, rebalancedPortfolio} {
+		for assetType := range holdings {
+			if !slices.Contains(assetTypes, assetType) {
+				assetTypes = append(assetTypes, assetType)
+			}
+		}
+	}
+	slices.SortFunc(assetTypes, models.CompareAssetTypes)
+
+	trades := []models.Trade{}
+	for _, assetType := range assetTypes {
+		amount := rebalancedPortfolio[assetType] - portfolio[assetType]
+		if amount != 0 {
+			trades = append(trades, models.Trade{AccountName: accountName, AssetType: assetType, Amount: amount})
+		}
+	}
+	return trades
+}
+
+// Sets the cost of each trade and deducts the total from the account, returning the total
+func payTransactionCosts(
+	account *models.Account,
+	trades []models.Trade,
+	forecastRequest models.ForecastPortfolioRequest,
+	portfolioAllocation models.PortfolioAllocation) models.Money {
+
+	totalCost := models.Money(0)
+	for i := range trades {
+		tradedAmount := max(trades[i].Amount, -trades[i].Amount)
+		trades[i].Cost = tradedAmount.MulRate(forecastRequest.TransactionCostBps*basisPoint) + forecastRequest.TradeFee
+		totalCost = totalCost + trades[i].Cost
+	}
+	if totalCost > 0 {
+		spreadAmountByAllocation(account, -totalCost, portfolioAllocation)
+	}
+	return totalCost
+}
@@ -0,0 +1,77 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+	"github.com/guilam34/financial_planner/test_utils"
+)
+
+func TestGetRebalancingTrades(t *testing.T) {
+	trades := getRebalancingTrades(
+		"Brokerage",
+		models.Portfolio{models.Equities: models.Dollars(70_000), models.Bonds: models.Dollars(30_000), models.Cash: models.Dollars(5_000)},
+		models.Portfolio{models.Equities: models.Dollars(63_000), models.Bonds: models.Dollars(42_000), models.Cash: 0})
+	expectedTrades := []models.Trade{
+		{AccountName: "Brokerage", AssetType: models.Equities, Amount: models.Dollars(-7_000)},
+		{AccountName: "Brokerage", AssetType: models.Bonds, Amount: models.Dollars(12_000)},
+		{AccountName: "Brokerage", AssetType: models.Cash, Amount: models.Dollars(-5_000)},
+	}
+	if len(trades) != len(expectedTrades) {
+		t.Fatalf("expected %v but got %v", expectedTrades, trades)
+	}
+	for i, expectedTrade := range expectedTrades {
+		if trades[i].AccountName != expectedTrade.AccountName ||
+			trades[i].AssetType != expectedTrade.AssetType ||
+			trades[i].Amount != expectedTrade.Amount {
+			t.Errorf("expected %v but got %v", expectedTrades, trades)
+		}
+	}
+
+	if untraded := getRebalancingTrades("", models.Portfolio{models.Equities: models.Dollars(1_000)}, models.Portfolio{models.Equities: models.Dollars(1_000)}); len(untraded) != 0 {
+		t.Errorf("expected no trades but got %v", untraded)
+	}
+}
+
+func TestForecastWithTransactionCosts(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 1,
+		PortfolioAllocation: models.PortfolioAllocation{
+			models.Equities: {ReturnRate: 0.5, Allocation: 0.6},
+			models.Bonds:    {Allocation: 0.4},
+		},
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(60_000), models.Bonds: models.Dollars(40_000)},
+		RebalancingStrategy: models.EveryNYearsByAlloc,
+		RebalanceCadence:    1,
+		TransactionCostBps:  10,
+		TradeFee:            models.Dollars(5),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// Growth leaves 90000 and 40000 so 12000 of equities are sold to buy bonds
+	if len(response.Trades[1]) != 2 || response.Trades[1][0].Cost != models.Dollars(17) {
+		t.Errorf("expected two trades costing 17 each but got %v", response.Trades[1])
+	}
+	if !test_utils.AlmostEqual(response.Turnover[1], 12_000.0/130_000) {
+		t.Errorf("expected turnover of 12000 over 130000 but got %v", response.Turnover[1])
+	}
+	if response.TransactionCosts[1] != models.Dollars(34) || response.TotalTransactionCosts != models.Dollars(34) {
+		t.Errorf("expected 34 of transaction costs but got %v", response.TransactionCosts)
+	}
+	endValue, _, _ := getNetPortfolioValue(response.Portfolios[1])
+	if endValue != models.Dollars(130_000-34) {
+		t.Errorf("expected costs to be deducted but got %v", response.Portfolios[1])
+	}
+}
+
+func TestNegativeTransactionCosts(t *testing.T) {
+	_, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		PortfolioAllocation: singleAssetAllocation,
+		TradeFee:            models.Dollars(-1),
+	})
+	expectedMessage := "transaction costs must be greater than or equal to 0"
+	if err == nil || err.Error() != expectedMessage {
+		t.Errorf("expected %v but got %v", expectedMessage, err)
+	}
+}
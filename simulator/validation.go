@@ -0,0 +1,326 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+func newValidationError(field string, code models.ValidationCode, message string) error {
+	return &models.ValidationError{FieldErrors: []models.FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+func addFieldError(validationErr *models.ValidationError, field string, code models.ValidationCode, message string) {
+	validationErr.FieldErrors = append(validationErr.FieldErrors, models.FieldError{Field: field, Code: code, Message: message})
+}
+
+// Adds the problems of errors found while building the simulation to those found with the request.
+// Errors that aren't about the request are returned as they are since the request can't be blamed.
+func joinValidationErrors(validationErr *models.ValidationError, errs ...error) error {
+	for _, err := range errs {
+		var otherValidationErr *models.ValidationError
+		if errors.As(err, &otherValidationErr) {
+			validationErr.FieldErrors = append(validationErr.FieldErrors, otherValidationErr.FieldErrors...)
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(validationErr.FieldErrors) == 0 {
+		return nil
+	}
+	return validationErr
+}
+
+// Points the fields of a nested request's problems at where the request is nested
+func prefixFieldErrors(err error, prefix string) error {
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	prefixedErr := &models.ValidationError{FieldErrors: slices.Clone(validationErr.FieldErrors)}
+	for i := range prefixedErr.FieldErrors {
+		prefixedErr.FieldErrors[i].Field = prefix + "." + prefixedErr.FieldErrors[i].Field
+	}
+	return prefixedErr
+}
+
+// Checks every field of the request that doesn't need tax tables, collecting all of the problems
+// rather than stopping at the first one. Fields that only matter to a simulation mode are checked
+// when that mode is chosen.
+func validateForecastRequest(forecastRequest models.ForecastPortfolioRequest) *models.ValidationError {
+	validationErr := &models.ValidationError{}
+	addError := func(field string, code models.ValidationCode, message string) {
+		addFieldError(validationErr, field, code, message)
+	}
+
+	if forecastRequest.EndYear < 0 || forecastRequest.EndYear > maxEndYear {
+		addError("EndYear", models.OutOfRange, fmt.Sprintf("end year must be between 0 and %d", maxEndYear))
+	}
+
+	for i, balanceChange := range forecastRequest.AnnualPortfolioBalanceChanges {
+		field := fmt.Sprintf("AnnualPortfolioBalanceChanges[%d]", i)
+		if balanceChange.EndYear > forecastRequest.EndYear {
+			addError(field+".EndYear", models.OutOfRange, "annual balance change end year must be less than or equal to last year")
+		}
+		if balanceChange.StartYear > balanceChange.EndYear {
+			addError(field+".StartYear", models.InvalidOrder, "annual balance change start year must be less than or equal to its end year")
+		}
+	}
+
+	allocatedPortfolioPct := 0.0
+	for _, assetType := range sortedAssetTypes(forecastRequest.PortfolioAllocation) {
+		allocation := forecastRequest.PortfolioAllocation[assetType]
+		field := "PortfolioAllocation." + string(assetType)
+		allocatedPortfolioPct = allocatedPortfolioPct + allocation.Allocation
+		if allocation.Allocation < 0 || allocation.Allocation > 1 {
+			addError(field+".Allocation", models.OutOfRange, "asset allocation must be between 0 and 1")
+		}
+		if allocation.Volatility < 0 {
+			addError(field+".Volatility", models.OutOfRange, "asset volatility must be greater than or equal to 0")
+		}
+		if allocation.ExpenseRatio < 0 || allocation.ExpenseRatio >= 1 {
+			addError(field+".ExpenseRatio", models.OutOfRange, "expense ratio must be greater than or equal to 0 and less than 1")
+		}
+	}
+	if !isWholeAllocation(allocatedPortfolioPct) {
+		addError("PortfolioAllocation", models.InvalidSum, "portfolio allocation percent must sum up to 1")
+	}
+	for _, assetType := range sortedAssetTypes(forecastRequest.InitPortfolio) {
+		if _, ok := forecastRequest.PortfolioAllocation[assetType]; !ok {
+			addError("InitPortfolio."+string(assetType), models.UnknownAssetType, "portfolio assets must be in the portfolio allocation")
+		}
+	}
+
+	for i, waypoint := range forecastRequest.AllocationSchedule {
+		field := fmt.Sprintf("AllocationSchedule[%d]", i)
+		if waypoint.Year < 1 || (i > 0 && waypoint.Year <= forecastRequest.AllocationSchedule[i-1].Year) {
+			addError(field+".Year", models.InvalidOrder, "allocation schedule years must be positive and increasing")
+		}
+		waypointPct := 0.0
+		for _, assetType := range sortedAssetTypes(waypoint.Allocations) {
+			allocation := waypoint.Allocations[assetType]
+			if _, ok := forecastRequest.PortfolioAllocation[assetType]; !ok {
+				addError(field+".Allocations."+string(assetType), models.UnknownAssetType, "allocation schedule assets must be in the portfolio allocation")
+			} else if allocation < 0 || allocation > 1 {
+				addError(field+".Allocations."+string(assetType), models.OutOfRange, "asset allocation must be between 0 and 1")
+			}
+			waypointPct = waypointPct + allocation
+		}
+		if !isWholeAllocation(waypointPct) {
+			addError(field+".Allocations", models.InvalidSum, "allocation schedule percents must sum up to 1")
+		}
+	}
+
+	if forecastRequest.RebalanceCadence < 0 ||
+		(forecastRequest.RebalancingStrategy == models.EveryNYearsByAlloc && forecastRequest.RebalanceCadence == 0) {
+		addError("RebalanceCadence", models.OutOfRange, "rebalance cadence must be greater than 0")
+	}
+	if forecastRequest.AbsoluteRebalanceBand < 0 || forecastRequest.AbsoluteRebalanceBand > 1 {
+		addError("AbsoluteRebalanceBand", models.OutOfRange, "rebalance bands must be between 0 and 1")
+	}
+	if forecastRequest.RelativeRebalanceBand < 0 || forecastRequest.RelativeRebalanceBand > 1 {
+		addError("RelativeRebalanceBand", models.OutOfRange, "rebalance bands must be between 0 and 1")
+	}
+	if forecastRequest.RebalancingStrategy == models.OutsideBandsByAlloc &&
+		forecastRequest.AbsoluteRebalanceBand == 0 && forecastRequest.RelativeRebalanceBand == 0 {
+		addError("AbsoluteRebalanceBand", models.MissingValue, "band rebalancing requires an absolute or relative band")
+	}
+
+	if forecastRequest.AdvisoryFeeRate < 0 || forecastRequest.AdvisoryFeeRate >= 1 {
+		addError("AdvisoryFeeRate", models.OutOfRange, "advisory fee rate must be greater than or equal to 0 and less than 1")
+	}
+	if forecastRequest.AdvisoryFeeRate > 0 && len(forecastRequest.AdvisoryFeeTiers) > 0 {
+		addError("AdvisoryFeeTiers", models.ConflictingFields, "advisory fee rate and tiers can't both be set")
+	}
+	for i, tier := range forecastRequest.AdvisoryFeeTiers {
+		field := fmt.Sprintf("AdvisoryFeeTiers[%d]", i)
+		if (i == 0 && tier.Threshold != 0) || (i > 0 && tier.Threshold <= forecastRequest.AdvisoryFeeTiers[i-1].Threshold) {
+			addError(field+".Threshold", models.InvalidOrder, "advisory fee tiers must start at 0 and increase")
+		}
+		if tier.Rate < 0 || tier.Rate >= 1 {
+			addError(field+".Rate", models.OutOfRange, "advisory fee rate must be greater than or equal to 0 and less than 1")
+		}
+	}
+
+	if forecastRequest.TransactionCostBps < 0 {
+		addError("TransactionCostBps", models.OutOfRange, "transaction costs must be greater than or equal to 0")
+	}
+	if forecastRequest.TradeFee < 0 {
+		addError("TradeFee", models.OutOfRange, "transaction costs must be greater than or equal to 0")
+	}
+
+	if len(forecastRequest.Accounts) > 0 && len(forecastRequest.InitPortfolio) > 0 {
+		addError("InitPortfolio", models.ConflictingFields, "initial portfolio must be empty when accounts are given")
+	}
+	for i, account := range forecastRequest.Accounts {
+		if findAccount(forecastRequest.Accounts, account.Name) != i {
+			addError(fmt.Sprintf("Accounts[%d].Name", i), models.DuplicateName, "account names must be unique")
+		}
+		for _, assetType := range sortedAssetTypes(account.Holdings) {
+			if _, ok := forecastRequest.PortfolioAllocation[assetType]; !ok {
+				addError(fmt.Sprintf("Accounts[%d].Holdings.%s", i, assetType), models.UnknownAssetType, "portfolio assets must be in the portfolio allocation")
+			}
+		}
+	}
+	for i, balanceChange := range forecastRequest.AnnualPortfolioBalanceChanges {
+		if balanceChange.AccountName != "" && findAccount(forecastRequest.Accounts, balanceChange.AccountName) < 0 {
+			addError(fmt.Sprintf("AnnualPortfolioBalanceChanges[%d].AccountName", i), models.UnknownAccount, "annual balance change account must be one of the accounts")
+		}
+	}
+	taxRates := forecastRequest.TaxRates
+	for _, taxRate := range []struct {
+		field string
+		rate  float64
+	}{
+		{"TaxRates.OrdinaryIncomeRate", taxRates.OrdinaryIncomeRate},
+		{"TaxRates.CapitalGainsRate", taxRates.CapitalGainsRate},
+		{"TaxRates.TaxableGrowthRate", taxRates.TaxableGrowthRate},
+	} {
+		if taxRate.rate < 0 || taxRate.rate >= 1 {
+			addError(taxRate.field, models.OutOfRange, "tax rates must be greater than or equal to 0 and less than 1")
+		}
+	}
+
+	if forecastRequest.TargetBracketRate < 0 || forecastRequest.TargetBracketRate > 1 {
+		addError("TargetBracketRate", models.OutOfRange, "target bracket rate must be between 0 and 1")
+	}
+
+	if forecastRequest.WithdrawalRate < 0 || forecastRequest.WithdrawalRate > 1 {
+		addError("WithdrawalRate", models.OutOfRange, "withdrawal rate must be between 0 and 1")
+	}
+	if forecastRequest.WithdrawalFloor < 0 {
+		addError("WithdrawalFloor", models.OutOfRange, "withdrawal floor and ceiling must be greater than or equal to 0")
+	}
+	if forecastRequest.WithdrawalCeiling < 0 {
+		addError("WithdrawalCeiling", models.OutOfRange, "withdrawal floor and ceiling must be greater than or equal to 0")
+	}
+	if forecastRequest.WithdrawalCeiling > 0 && forecastRequest.WithdrawalFloor > forecastRequest.WithdrawalCeiling {
+		addError("WithdrawalFloor", models.InvalidOrder, "withdrawal floor must be less than or equal to the ceiling")
+	}
+	if forecastRequest.GuardrailThreshold < 0 || forecastRequest.GuardrailThreshold > 1 {
+		addError("GuardrailThreshold", models.OutOfRange, "guardrail threshold and adjustment must be between 0 and 1")
+	}
+	if forecastRequest.GuardrailAdjustment < 0 || forecastRequest.GuardrailAdjustment > 1 {
+		addError("GuardrailAdjustment", models.OutOfRange, "guardrail threshold and adjustment must be between 0 and 1")
+	}
+	if forecastRequest.WithdrawalStrategy == models.RmdStyle && forecastRequest.OwnerBirthYear == 0 {
+		addError("OwnerBirthYear", models.MissingValue, "rmd style withdrawals require the owner birth year")
+	}
+
+	if forecastRequest.OwnerBirthYear != 0 && forecastRequest.OwnerBirthYear > forecastRequest.CurrentYear {
+		addError("OwnerBirthYear", models.OutOfRange, "owner birth year must be less than or equal to the current year")
+	}
+
+	for i, conversion := range forecastRequest.RothConversions {
+		field := fmt.Sprintf("RothConversions[%d]", i)
+		if conversion.EndYear > forecastRequest.EndYear {
+			addError(field+".EndYear", models.OutOfRange, "roth conversion end year must be less than or equal to last year")
+		}
+		if conversion.Amount < 0 {
+			addError(field+".Amount", models.OutOfRange, "roth conversion amount must be greater than or equal to 0")
+		}
+		if conversion.TargetBracketRate < 0 || conversion.TargetBracketRate > 1 {
+			addError(field+".TargetBracketRate", models.OutOfRange, "roth conversion target bracket rate must be between 0 and 1")
+		}
+		fromIdx := findRothConversionAccount(forecastRequest.Accounts, conversion.FromAccountName, models.TaxDeferred)
+		if fromIdx < 0 || forecastRequest.Accounts[fromIdx].AccountType != models.TaxDeferred {
+			addError(field+".FromAccountName", models.UnknownAccount, "roth conversions must convert from a tax-deferred account")
+		}
+		toIdx := findRothConversionAccount(forecastRequest.Accounts, conversion.ToAccountName, models.TaxFree)
+		if toIdx < 0 || forecastRequest.Accounts[toIdx].AccountType != models.TaxFree {
+			addError(field+".ToAccountName", models.UnknownAccount, "roth conversions must convert into a tax-free account")
+		}
+	}
+
+	if len(forecastRequest.SocialSecurityBenefits) > 2 {
+		addError("SocialSecurityBenefits", models.OutOfRange, "social security benefits are limited to two spouses")
+	}
+	for i, benefit := range forecastRequest.SocialSecurityBenefits {
+		field := fmt.Sprintf("SocialSecurityBenefits[%d]", i)
+		if benefit.PrimaryInsuranceAmount < 0 {
+			addError(field+".PrimaryInsuranceAmount", models.OutOfRange, "primary insurance amount must be greater than or equal to 0")
+		}
+		if benefit.ClaimingAge < minClaimingAge || benefit.ClaimingAge > maxClaimingAge {
+			addError(field+".ClaimingAge", models.OutOfRange, "social security claiming age must be between 62 and 70")
+		}
+		if benefit.BirthYear > forecastRequest.CurrentYear {
+			addError(field+".BirthYear", models.OutOfRange, "social security birth year must be less than or equal to the current year")
+		}
+	}
+
+	for i, incomeSource := range forecastRequest.IncomeSources {
+		field := fmt.Sprintf("IncomeSources[%d]", i)
+		if incomeSource.AnnualAmount < 0 {
+			addError(field+".AnnualAmount", models.OutOfRange, "income source amounts must be greater than or equal to 0")
+		}
+		if incomeSource.Premium < 0 {
+			addError(field+".Premium", models.OutOfRange, "income source amounts must be greater than or equal to 0")
+		}
+		if incomeSource.SurvivorFraction < 0 || incomeSource.SurvivorFraction > 1 {
+			addError(field+".SurvivorFraction", models.OutOfRange, "survivor fraction must be between 0 and 1")
+		}
+		if incomeSource.BirthYear > forecastRequest.CurrentYear {
+			addError(field+".BirthYear", models.OutOfRange, "income source birth year must be less than or equal to the current year")
+		}
+		if incomeSource.IncomeSourceType == models.Annuity && incomeSource.StartAge < incomeSource.PurchaseAge {
+			addError(field+".StartAge", models.InvalidOrder, "annuity payments must start at or after the purchase age")
+		}
+		if incomeSource.PremiumAccountName != "" && findAccount(forecastRequest.Accounts, incomeSource.PremiumAccountName) < 0 {
+			addError(field+".PremiumAccountName", models.UnknownAccount, "annuity premium account must be one of the accounts")
+		}
+	}
+
+	if forecastRequest.AnnualInflationVolatility < 0 {
+		addError("AnnualInflationVolatility", models.OutOfRange, "inflation volatility must be greater than or equal to 0")
+	}
+
+	if forecastRequest.NumSimulations < 0 || forecastRequest.NumSimulations > maxNumSimulations {
+		addError("NumSimulations", models.OutOfRange, "number of simulations must be between 0 and 100000")
+	}
+
+	switch forecastRequest.SimulationMode {
+	case models.MonteCarlo:
+		checkCorrelation := func(field string, assetTypes []models.AssetType, correlation float64) {
+			for _, assetType := range assetTypes {
+				if _, ok := forecastRequest.PortfolioAllocation[assetType]; !ok {
+					addError(field, models.UnknownAssetType, "correlated asset types must be in the portfolio allocation")
+					return
+				}
+			}
+			if correlation < -1.0 || correlation > 1.0 {
+				addError(field, models.OutOfRange, "correlation must be between -1 and 1")
+			}
+		}
+		for i, assetCorrelation := range forecastRequest.AssetCorrelations {
+			checkCorrelation(
+				fmt.Sprintf("AssetCorrelations[%d]", i),
+				[]models.AssetType{assetCorrelation.AssetType, assetCorrelation.OtherAssetType},
+				assetCorrelation.Correlation)
+		}
+		for _, assetType := range sortedAssetTypes(forecastRequest.InflationCorrelations) {
+			checkCorrelation(
+				"InflationCorrelations."+string(assetType),
+				[]models.AssetType{assetType},
+				forecastRequest.InflationCorrelations[assetType])
+		}
+		break
+	case models.Bootstrap:
+		// Problems loading the historical data are left for the simulation to report
+		historicalReturns, err := loadHistoricalReturns()
+		if forecastRequest.BootstrapBlockLength < 0 {
+			addError("BootstrapBlockLength", models.OutOfRange, "bootstrap block length must be greater than or equal to 0")
+		} else if err == nil && forecastRequest.BootstrapBlockLength > len(historicalReturns) {
+			addError("BootstrapBlockLength", models.UnavailableData, "bootstrap block length exceeds the available historical data")
+		}
+		break
+	case models.Historical:
+		historicalReturns, err := loadHistoricalReturns()
+		if err == nil && max(forecastRequest.EndYear, 1) > len(historicalReturns) {
+			addError("EndYear", models.UnavailableData, "end year exceeds the available historical data")
+		}
+		break
+	}
+	return validationErr
+}
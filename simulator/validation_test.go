@@ -0,0 +1,177 @@
+package simulator
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type ValidationTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	FieldErrors     []models.FieldError
+}
+
+var validationCases = []ValidationTestCase{
+	{
+		CaseName: "ReportsEveryProblemAtOnce",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear: -1,
+			AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+				{Amount: models.Dollars(1_000), StartYear: 0, EndYear: -1},
+			},
+			PortfolioAllocation: models.PortfolioAllocation{
+				models.Equities: {Allocation: 1.5},
+				models.Bonds:    {Allocation: -0.5},
+			},
+			InitPortfolio:       models.Portfolio{"REIT": models.Dollars(100_000)},
+			RebalancingStrategy: models.EveryNYearsByAlloc,
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "EndYear", Code: models.OutOfRange, Message: "end year must be between 0 and 150"},
+			{Field: "AnnualPortfolioBalanceChanges[0].StartYear", Code: models.InvalidOrder,
+				Message: "annual balance change start year must be less than or equal to its end year"},
+			{Field: "PortfolioAllocation.equities.Allocation", Code: models.OutOfRange, Message: "asset allocation must be between 0 and 1"},
+			{Field: "PortfolioAllocation.bonds.Allocation", Code: models.OutOfRange, Message: "asset allocation must be between 0 and 1"},
+			{Field: "InitPortfolio.REIT", Code: models.UnknownAssetType, Message: "portfolio assets must be in the portfolio allocation"},
+			{Field: "RebalanceCadence", Code: models.OutOfRange, Message: "rebalance cadence must be greater than 0"},
+		},
+	},
+	{
+		CaseName: "ReportsModelProblemsWithTheRest",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: models.PortfolioAllocation{models.Equities: {Allocation: 0.5}},
+			Accounts: []models.Account{
+				{Name: "Roth", AccountType: models.TaxFree, Holdings: models.Portfolio{models.Cash: models.Dollars(1_000)}},
+			},
+			InflationModel:         models.MeanRevertingInflation,
+			InflationMeanReversion: 2,
+			TaxCalculator:          models.BracketTax,
+			TaxYear:                1900,
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "PortfolioAllocation", Code: models.InvalidSum, Message: "portfolio allocation percent must sum up to 1"},
+			{Field: "Accounts[0].Holdings.cash", Code: models.UnknownAssetType, Message: "portfolio assets must be in the portfolio allocation"},
+			{Field: "InflationMeanReversion", Code: models.OutOfRange, Message: "inflation mean reversion must be between 0 and 1"},
+			{Field: "TaxYear", Code: models.UnavailableData, Message: "no federal tax table is available for the tax year"},
+		},
+	},
+	{
+		CaseName: "MonteCarloCorrelations",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: models.PortfolioAllocation{models.Equities: {Allocation: 1.0}},
+			SimulationMode:      models.MonteCarlo,
+			AssetCorrelations: []models.AssetCorrelation{
+				{AssetType: models.Equities, OtherAssetType: models.Bonds, Correlation: 0.1},
+			},
+			InflationCorrelations: map[models.AssetType]float64{models.Equities: 1.5},
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "AssetCorrelations[0]", Code: models.UnknownAssetType, Message: "correlated asset types must be in the portfolio allocation"},
+			{Field: "InflationCorrelations.equities", Code: models.OutOfRange, Message: "correlation must be between -1 and 1"},
+		},
+	},
+	{
+		CaseName: "BootstrapBlockLength",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:              -1,
+			PortfolioAllocation:  models.PortfolioAllocation{models.Equities: {Allocation: 1.0}},
+			SimulationMode:       models.Bootstrap,
+			BootstrapBlockLength: -1,
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "EndYear", Code: models.OutOfRange, Message: "end year must be between 0 and 150"},
+			{Field: "BootstrapBlockLength", Code: models.OutOfRange, Message: "bootstrap block length must be greater than or equal to 0"},
+		},
+	},
+	{
+		CaseName: "HistoricalEndYear",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             120,
+			PortfolioAllocation: models.PortfolioAllocation{models.Equities: {Allocation: 0.5}},
+			SimulationMode:      models.Historical,
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "PortfolioAllocation", Code: models.InvalidSum, Message: "portfolio allocation percent must sum up to 1"},
+			{Field: "EndYear", Code: models.UnavailableData, Message: "end year exceeds the available historical data"},
+		},
+	},
+	{
+		CaseName: "RebalanceBandsAreFractions",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation:   models.PortfolioAllocation{models.Equities: {Allocation: 1.0}},
+			RebalancingStrategy:   models.OutsideBandsByAlloc,
+			AbsoluteRebalanceBand: 5,
+			RelativeRebalanceBand: 25,
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "AbsoluteRebalanceBand", Code: models.OutOfRange, Message: "rebalance bands must be between 0 and 1"},
+			{Field: "RelativeRebalanceBand", Code: models.OutOfRange, Message: "rebalance bands must be between 0 and 1"},
+		},
+	},
+	{
+		CaseName: "TooManySimulations",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: models.PortfolioAllocation{models.Equities: {Allocation: 1.0}},
+			SimulationMode:      models.MonteCarlo,
+			NumSimulations:      1_000_000,
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "NumSimulations", Code: models.OutOfRange, Message: "number of simulations must be between 0 and 100000"},
+		},
+	},
+	{
+		CaseName: "EndYearTooFar",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             1_000_000,
+			PortfolioAllocation: models.PortfolioAllocation{models.Equities: {Allocation: 1.0}},
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "EndYear", Code: models.OutOfRange, Message: "end year must be between 0 and 150"},
+		},
+	},
+	{
+		CaseName: "ModeFieldsIgnoredInOtherModes",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:              120,
+			PortfolioAllocation:  models.PortfolioAllocation{models.Equities: {Allocation: 0.5}},
+			BootstrapBlockLength: -1,
+			AssetCorrelations: []models.AssetCorrelation{
+				{AssetType: models.Equities, OtherAssetType: models.Bonds, Correlation: 0.1},
+			},
+		},
+		FieldErrors: []models.FieldError{
+			{Field: "PortfolioAllocation", Code: models.InvalidSum, Message: "portfolio allocation percent must sum up to 1"},
+		},
+	},
+}
+
+func TestValidationCases(t *testing.T) {
+	for _, test := range validationCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a validation error but got %v", err)
+			}
+			if !slices.Equal(validationErr.FieldErrors, test.FieldErrors) {
+				t.Errorf("expected %v but got %v", test.FieldErrors, validationErr.FieldErrors)
+			}
+		})
+	}
+}
+
+func TestSocialSecurityClaimingValidationFieldsAreNested(t *testing.T) {
+	_, err := OptimizeSocialSecurityClaiming(models.SocialSecurityClaimingRequest{
+		ForecastRequest: models.ForecastPortfolioRequest{
+			SocialSecurityBenefits: []models.SocialSecurityBenefit{{PrimaryInsuranceAmount: models.Dollars(2_000)}},
+		},
+	})
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.FieldErrors) != 1 ||
+		validationErr.FieldErrors[0].Field != "ForecastRequest.PortfolioAllocation" {
+		t.Errorf("expected the allocation sum error within the forecast request but got %v", err)
+	}
+}
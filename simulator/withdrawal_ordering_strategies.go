@@ -0,0 +1,177 @@
+package simulator
+
+import (
+	"github.com/guilam34/financial_planner/models"
+)
+
+// Decides which accounts fund the household's withdrawals
+type WithdrawalOrderingStrategy interface {
+	Withdraw(
+		accounts []models.Account,
+		netAmount models.Money,
+		portfolioAllocation models.PortfolioAllocation,
+		income *taxableIncome) (unfundedAmount models.Money, withdrawals []models.AccountWithdrawal)
+}
+
+// Exhausts every account of a type before moving onto the next type
+type WithdrawByAccountType struct {
+	accountTypeOrder []models.AccountTypeEnum
+}
+
+func (w WithdrawByAccountType) Withdraw(
+	accounts []models.Account,
+	netAmount models.Money,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) (models.Money, []models.AccountWithdrawal) {
+
+	withdrawals := []models.AccountWithdrawal{}
+	unfundedAmount := withdrawFromAccountTypes(
+		accounts, netAmount, w.accountTypeOrder, portfolioAllocation, income, &withdrawals)
+	return unfundedAmount, withdrawals
+}
+
+// Draws from every account in proportion to its value. Anything an account can't cover after taxes
+// falls back to the taxable first order.
+type WithdrawProportionally struct{}
+
+func (w WithdrawProportionally) Withdraw(
+	accounts []models.Account,
+	netAmount models.Money,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) (models.Money, []models.AccountWithdrawal) {
+
+	householdValue := models.Money(0)
+	accountValues := make([]models.Money, len(accounts))
+	for i, account := range accounts {
+		accountValues[i], _, _ = getNetPortfolioValue(account.Holdings)
+		householdValue = householdValue + max(0, accountValues[i])
+	}
+
+	withdrawals := []models.AccountWithdrawal{}
+	unfundedAmount := netAmount
+	if householdValue > 0 {
+		accountWeights := make([]float64, len(accounts))
+		for i, accountValue := range accountValues {
+			accountWeights[i] = float64(max(0, accountValue)) / float64(householdValue)
+		}
+		for i, accountShare := range splitAmount(netAmount, accountWeights) {
+			withdrawal := withdrawFromAccount(&accounts[i], accountShare, unlimitedAmount, portfolioAllocation, income)
+			withdrawals = recordWithdrawal(withdrawals, withdrawal)
+			unfundedAmount = unfundedAmount - withdrawal.NetAmount
+		}
+	}
+	unfundedAmount = withdrawFromAccountTypes(
+		accounts, unfundedAmount, taxableFirstOrder, portfolioAllocation, income, &withdrawals)
+	return unfundedAmount, withdrawals
+}
+
+// Draws tax-deferred accounts only until ordinary income would be taxed above the target bracket,
+// then tax-free and taxable accounts, and only then the rest of the tax-deferred accounts
+type WithdrawFillingBracket struct {
+	targetBracketRate float64
+}
+
+func (w WithdrawFillingBracket) Withdraw(
+	accounts []models.Account,
+	netAmount models.Money,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome) (models.Money, []models.AccountWithdrawal) {
+
+	withdrawals := []models.AccountWithdrawal{}
+	unfundedAmount := netAmount
+	bracketCeiling := income.taxCalculator.GetBracketCeiling(w.targetBracketRate)
+	for i := range accounts {
+		if accounts[i].AccountType != models.TaxDeferred {
+			continue
+		}
+		bracketRoom := bracketCeiling - income.ordinaryIncome - income.socialSecurityIncome
+		withdrawal := withdrawFromAccount(&accounts[i], unfundedAmount, bracketRoom, portfolioAllocation, income)
+		withdrawals = recordWithdrawal(withdrawals, withdrawal)
+		unfundedAmount = unfundedAmount - withdrawal.NetAmount
+	}
+	unfundedAmount = withdrawFromAccountTypes(
+		accounts,
+		unfundedAmount,
+		[]models.AccountTypeEnum{models.TaxFree, models.Taxable, models.TaxDeferred, models.HealthSavings},
+		portfolioAllocation,
+		income,
+		&withdrawals)
+	return unfundedAmount, withdrawals
+}
+
+// Accounts are drawn down starting with the ones whose withdrawals lose the most to taxes
+var taxableFirstOrder = []models.AccountTypeEnum{
+	models.Taxable,
+	models.TaxDeferred,
+	models.TaxFree,
+	models.HealthSavings,
+}
+
+var taxDeferredFirstOrder = []models.AccountTypeEnum{
+	models.TaxDeferred,
+	models.Taxable,
+	models.TaxFree,
+	models.HealthSavings,
+}
+
+func getWithdrawalOrderingStrategy(forecastRequest models.ForecastPortfolioRequest) WithdrawalOrderingStrategy {
+	var withdrawalOrderingStrategy WithdrawalOrderingStrategy
+	switch forecastRequest.WithdrawalOrder {
+	case models.TaxDeferredFirst:
+		withdrawalOrderingStrategy = WithdrawByAccountType{accountTypeOrder: taxDeferredFirstOrder}
+		break
+	case models.ProportionalWithdrawal:
+		withdrawalOrderingStrategy = WithdrawProportionally{}
+		break
+	case models.FillTheBracket:
+		withdrawalOrderingStrategy = WithdrawFillingBracket{targetBracketRate: forecastRequest.TargetBracketRate}
+		break
+	default:
+		withdrawalOrderingStrategy = WithdrawByAccountType{accountTypeOrder: taxableFirstOrder}
+		break
+	}
+	return withdrawalOrderingStrategy
+}
+
+// Funds the net amount from accounts in the order of their types, returning the amount that could
+// not be funded
+func withdrawFromAccountTypes(
+	accounts []models.Account,
+	netAmount models.Money,
+	accountTypeOrder []models.AccountTypeEnum,
+	portfolioAllocation models.PortfolioAllocation,
+	income *taxableIncome,
+	withdrawals *[]models.AccountWithdrawal) models.Money {
+
+	unfundedAmount := netAmount
+	for _, accountType := range accountTypeOrder {
+		for i := range accounts {
+			if accounts[i].AccountType != accountType {
+				continue
+			}
+			withdrawal := withdrawFromAccount(&accounts[i], unfundedAmount, unlimitedAmount, portfolioAllocation, income)
+			*withdrawals = recordWithdrawal(*withdrawals, withdrawal)
+			unfundedAmount = unfundedAmount - withdrawal.NetAmount
+		}
+	}
+	return unfundedAmount
+}
+
+// Adds the withdrawal to the account's existing withdrawal for the year, if any
+func recordWithdrawal(
+	withdrawals []models.AccountWithdrawal,
+	withdrawal models.AccountWithdrawal) []models.AccountWithdrawal {
+
+	if withdrawal.GrossAmount <= 0 {
+		return withdrawals
+	}
+	for i := range withdrawals {
+		if withdrawals[i].AccountName == withdrawal.AccountName {
+			withdrawals[i].GrossAmount = withdrawals[i].GrossAmount + withdrawal.GrossAmount
+			withdrawals[i].NetAmount = withdrawals[i].NetAmount + withdrawal.NetAmount
+			withdrawals[i].TaxPaid = withdrawals[i].TaxPaid + withdrawal.TaxPaid
+			return withdrawals
+		}
+	}
+	return append(withdrawals, withdrawal)
+}
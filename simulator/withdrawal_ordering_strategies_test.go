@@ -0,0 +1,168 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type WithdrawalOrderingTestCase struct {
+	CaseName           string
+	Strategy           WithdrawalOrderingStrategy
+	TaxCalculator      TaxCalculator
+	Benefits           float64
+	NetAmount          float64
+	UnfundedAmount     float64
+	EndValues          []float64
+	AccountWithdrawals []models.AccountWithdrawal
+}
+
+func newOrderingTestAccounts() []models.Account {
+	return []models.Account{
+		{Name: "Roth", AccountType: models.TaxFree, Holdings: models.Portfolio{models.Equities: models.Dollars(50_000)}},
+		{Name: "401k", AccountType: models.TaxDeferred, Holdings: models.Portfolio{models.Equities: models.Dollars(100_000)}},
+		{Name: "Brokerage", AccountType: models.Taxable, Holdings: models.Portfolio{models.Equities: models.Dollars(50_000)}, CostBasis: models.Dollars(50_000)},
+	}
+}
+
+var flatTwentyPercentTax = FlatTaxCalculator{taxRates: models.TaxRates{OrdinaryIncomeRate: 0.2}}
+
+var withdrawalOrderingCases = []WithdrawalOrderingTestCase{
+	{
+		CaseName:      "TaxableFirst",
+		Strategy:      WithdrawByAccountType{accountTypeOrder: taxableFirstOrder},
+		TaxCalculator: flatTwentyPercentTax,
+		NetAmount:     70_000,
+		EndValues:     []float64{50_000, 75_000, 0},
+		AccountWithdrawals: []models.AccountWithdrawal{
+			{AccountName: "Brokerage", GrossAmount: models.Dollars(50_000), NetAmount: models.Dollars(50_000)},
+			{AccountName: "401k", GrossAmount: models.Dollars(25_000), NetAmount: models.Dollars(20_000), TaxPaid: models.Dollars(5_000)},
+		},
+	},
+	{
+		CaseName:      "TaxDeferredFirst",
+		Strategy:      WithdrawByAccountType{accountTypeOrder: taxDeferredFirstOrder},
+		TaxCalculator: flatTwentyPercentTax,
+		NetAmount:     40_000,
+		EndValues:     []float64{50_000, 50_000, 50_000},
+		AccountWithdrawals: []models.AccountWithdrawal{
+			{AccountName: "401k", GrossAmount: models.Dollars(50_000), NetAmount: models.Dollars(40_000), TaxPaid: models.Dollars(10_000)},
+		},
+	},
+	{
+		CaseName:      "Proportional",
+		Strategy:      WithdrawProportionally{},
+		TaxCalculator: flatTwentyPercentTax,
+		NetAmount:     40_000,
+		EndValues:     []float64{40_000, 75_000, 40_000},
+		AccountWithdrawals: []models.AccountWithdrawal{
+			{AccountName: "Roth", GrossAmount: models.Dollars(10_000), NetAmount: models.Dollars(10_000)},
+			{AccountName: "401k", GrossAmount: models.Dollars(25_000), NetAmount: models.Dollars(20_000), TaxPaid: models.Dollars(5_000)},
+			{AccountName: "Brokerage", GrossAmount: models.Dollars(10_000), NetAmount: models.Dollars(10_000)},
+		},
+	},
+	{
+		CaseName:       "ProportionalBeyondHouseholdValue",
+		Strategy:       WithdrawProportionally{},
+		TaxCalculator:  flatTwentyPercentTax,
+		NetAmount:      200_000,
+		UnfundedAmount: 20_000,
+		EndValues:      []float64{0, 0, 0},
+		AccountWithdrawals: []models.AccountWithdrawal{
+			{AccountName: "Roth", GrossAmount: models.Dollars(50_000), NetAmount: models.Dollars(50_000)},
+			{AccountName: "401k", GrossAmount: models.Dollars(100_000), NetAmount: models.Dollars(80_000), TaxPaid: models.Dollars(20_000)},
+			{AccountName: "Brokerage", GrossAmount: models.Dollars(50_000), NetAmount: models.Dollars(50_000)},
+		},
+	},
+	{
+		CaseName:      "FillTheBracket",
+		Strategy:      WithdrawFillingBracket{targetBracketRate: 0.12},
+		TaxCalculator: BracketTaxCalculator{federalSchedule: mustFindFederalSchedule(2024, "Single")},
+		NetAmount:     70_000,
+		// The 12% bracket tops out at 47150 of taxable income, or 61750 before the standard deduction
+		EndValues: []float64{36_324, 38_250, 50_000},
+		AccountWithdrawals: []models.AccountWithdrawal{
+			{AccountName: "401k", GrossAmount: models.Dollars(61_750), NetAmount: models.Dollars(56_324), TaxPaid: models.Dollars(5_426)},
+			{AccountName: "Roth", GrossAmount: models.Dollars(13_676), NetAmount: models.Dollars(13_676)},
+		},
+	},
+	{
+		CaseName:      "FillTheBracketWithBenefits",
+		Strategy:      WithdrawFillingBracket{targetBracketRate: 0.12},
+		TaxCalculator: BracketTaxCalculator{federalSchedule: mustFindFederalSchedule(2024, "Single")},
+		Benefits:      20_000,
+		NetAmount:     70_000,
+		// Benefits already take up 20000 of the 61750 that fits in the 12% bracket
+		EndValues: []float64{16_864, 58_250, 50_000},
+		AccountWithdrawals: []models.AccountWithdrawal{
+			{AccountName: "401k", GrossAmount: models.Dollars(41_750), NetAmount: models.Dollars(36_864), TaxPaid: models.Dollars(4_886)},
+			{AccountName: "Roth", GrossAmount: models.Dollars(33_136), NetAmount: models.Dollars(33_136)},
+		},
+	},
+}
+
+func mustFindFederalSchedule(taxYear int, filingStatus string) taxSchedule {
+	taxTables, _ := loadTaxTables()
+	return taxTables[taxYear].Federal[filingStatus]
+}
+
+func TestWithdrawalOrderingStrategies(t *testing.T) {
+	for _, test := range withdrawalOrderingCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			accounts := newOrderingTestAccounts()
+			unfundedAmount, withdrawals := test.Strategy.Withdraw(
+				accounts,
+				models.Dollars(test.NetAmount),
+				singleAssetAllocation,
+				&taxableIncome{taxCalculator: test.TaxCalculator, socialSecurityIncome: models.Dollars(test.Benefits)})
+
+			if unfundedAmount != models.Dollars(test.UnfundedAmount) {
+				t.Errorf("expected %v unfunded but got %v", test.UnfundedAmount, unfundedAmount)
+			}
+			for i, account := range accounts {
+				if actualVal := account.Holdings[models.Equities]; actualVal != models.Dollars(test.EndValues[i]) {
+					t.Errorf("expected %v in %s but got %v", test.EndValues[i], account.Name, actualVal)
+				}
+			}
+			if len(withdrawals) != len(test.AccountWithdrawals) {
+				t.Fatalf("expected %v but got %v", test.AccountWithdrawals, withdrawals)
+			}
+			for i, expected := range test.AccountWithdrawals {
+				actual := withdrawals[i]
+				if actual.AccountName != expected.AccountName ||
+					actual.GrossAmount != expected.GrossAmount ||
+					actual.NetAmount != expected.NetAmount ||
+					actual.TaxPaid != expected.TaxPaid {
+					t.Errorf("expected %v but got %v", expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastReportsAccountWithdrawals(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear: 1,
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-10_000), StartYear: 1, EndYear: 1, AccountName: "Roth"},
+			{Amount: models.Dollars(-20_000), StartYear: 1, EndYear: 1},
+		},
+		PortfolioAllocation: singleAssetAllocation,
+		Accounts:            newOrderingTestAccounts(),
+		TaxRates:            models.TaxRates{OrdinaryIncomeRate: 0.2},
+		WithdrawalOrder:     models.TaxDeferredFirst,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	withdrawals := response.AccountWithdrawals[1]
+	if len(withdrawals) != 2 ||
+		withdrawals[0].AccountName != "Roth" || withdrawals[0].NetAmount != models.Dollars(10_000) ||
+		withdrawals[1].AccountName != "401k" || withdrawals[1].GrossAmount != models.Dollars(25_000) {
+		t.Errorf("expected withdrawals from the Roth and then the 401k but got %v", withdrawals)
+	}
+	if response.TaxesPaid[1] != models.Dollars(5_000) {
+		t.Errorf("expected 5000 of tax but got %v", response.TaxesPaid[1])
+	}
+}
@@ -0,0 +1,173 @@
+package simulator
+
+import (
+	"math"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+const (
+	defaultGuardrailThreshold  = 0.2
+	defaultGuardrailAdjustment = 0.1
+	// Guyton-Klinger stops cutting spending once this few years are left
+	capitalPreservationCutoffYears = 15
+)
+
+// Sets the household's spending for a simulated year from the portfolio's value at the start of the
+// year. Strategies can carry state between years so each path builds its own.
+type WithdrawalStrategy interface {
+	NextYear(year int, portfolioValue models.Money) models.Money
+}
+
+type FixedWithdrawalStrategy struct{}
+
+func (f FixedWithdrawalStrategy) NextYear(year int, portfolioValue models.Money) models.Money {
+	return 0
+}
+
+type ConstantDollarStrategy struct {
+	withdrawalRate    float64
+	initialWithdrawal models.Money
+}
+
+func (c *ConstantDollarStrategy) NextYear(year int, portfolioValue models.Money) models.Money {
+	if year == 1 {
+		c.initialWithdrawal = portfolioValue.MulRate(c.withdrawalRate)
+	}
+	return c.initialWithdrawal
+}
+
+// Guardrails from Guyton and Klinger's decision rules. Spending is already in today's dollars so the
+// inflation rule isn't modeled.
+type GuytonKlingerStrategy struct {
+	initialWithdrawalRate float64
+	guardrailThreshold    float64
+	guardrailAdjustment   float64
+	endYear               int
+	prevWithdrawal        models.Money
+}
+
+func (g *GuytonKlingerStrategy) NextYear(year int, portfolioValue models.Money) models.Money {
+	if year == 1 {
+		g.prevWithdrawal = portfolioValue.MulRate(g.initialWithdrawalRate)
+		return g.prevWithdrawal
+	}
+	withdrawal := g.prevWithdrawal
+	if portfolioValue > 0 {
+		withdrawalRate := float64(withdrawal) / float64(portfolioValue)
+		remainingYears := g.endYear - year + 1
+		if withdrawalRate > g.initialWithdrawalRate*(1+g.guardrailThreshold) && remainingYears > capitalPreservationCutoffYears {
+			withdrawal = withdrawal.MulRate(1 - g.guardrailAdjustment)
+		} else if withdrawalRate < g.initialWithdrawalRate*(1-g.guardrailThreshold) {
+			withdrawal = withdrawal.MulRate(1 + g.guardrailAdjustment)
+		}
+	}
+	g.prevWithdrawal = withdrawal
+	return withdrawal
+}
+
+type VariablePercentageStrategy struct {
+	expectedReturnRate float64
+	endYear            int
+}
+
+func (v VariablePercentageStrategy) NextYear(year int, portfolioValue models.Money) models.Money {
+	remainingYears := float64(v.endYear - year + 1)
+	if v.expectedReturnRate == 0.0 {
+		return portfolioValue.MulRate(1 / remainingYears)
+	}
+	// Payment at the end of each remaining year of an annuity worth the portfolio
+	return portfolioValue.MulRate(v.expectedReturnRate / (1 - math.Pow(1+v.expectedReturnRate, -remainingYears)))
+}
+
+type PercentOfPortfolioStrategy struct {
+	withdrawalRate    float64
+	withdrawalFloor   models.Money
+	withdrawalCeiling models.Money
+}
+
+func (p PercentOfPortfolioStrategy) NextYear(year int, portfolioValue models.Money) models.Money {
+	withdrawal := max(portfolioValue.MulRate(p.withdrawalRate), p.withdrawalFloor)
+	if p.withdrawalCeiling > 0 {
+		withdrawal = min(withdrawal, p.withdrawalCeiling)
+	}
+	return withdrawal
+}
+
+type RmdStyleStrategy struct {
+	forecastRequest models.ForecastPortfolioRequest
+}
+
+func (r RmdStyleStrategy) NextYear(year int, portfolioValue models.Money) models.Money {
+	age := getCalendarYear(r.forecastRequest, year) - r.forecastRequest.OwnerBirthYear
+	return portfolioValue.MulRate(1 / getRmdStyleDistributionPeriod(uniformLifetimeTable, age))
+}
+
+// Extends the Uniform Lifetime Table to younger ages by adding a year to the period for every year
+// before the table starts
+func getRmdStyleDistributionPeriod(distributionPeriods map[int]float64, age int) float64 {
+	minAge := math.MaxInt
+	for tableAge := range distributionPeriods {
+		minAge = min(minAge, tableAge)
+	}
+	if age < minAge {
+		return distributionPeriods[minAge] + float64(minAge-age)
+	}
+	return getDistributionPeriod(distributionPeriods, age)
+}
+
+// Real return expected from the allocation without any volatility
+func getExpectedRealReturnRate(forecastRequest models.ForecastPortfolioRequest) float64 {
+	expectedReturnRate := 0.0
+	for _, allocation := range forecastRequest.PortfolioAllocation {
+		expectedReturnRate = expectedReturnRate + allocation.Allocation*allocation.ReturnRate
+	}
+	return expectedReturnRate - forecastRequest.AnnualInflationRate
+}
+
+// Builds a fresh withdrawal strategy for a single path since some strategies carry state between years
+func newWithdrawalStrategy(forecastRequest models.ForecastPortfolioRequest) WithdrawalStrategy {
+	guardrailThreshold := forecastRequest.GuardrailThreshold
+	if guardrailThreshold == 0.0 {
+		guardrailThreshold = defaultGuardrailThreshold
+	}
+	guardrailAdjustment := forecastRequest.GuardrailAdjustment
+	if guardrailAdjustment == 0.0 {
+		guardrailAdjustment = defaultGuardrailAdjustment
+	}
+
+	var withdrawalStrategy WithdrawalStrategy
+	switch forecastRequest.WithdrawalStrategy {
+	case models.ConstantDollar:
+		withdrawalStrategy = &ConstantDollarStrategy{withdrawalRate: forecastRequest.WithdrawalRate}
+		break
+	case models.GuytonKlinger:
+		withdrawalStrategy = &GuytonKlingerStrategy{
+			initialWithdrawalRate: forecastRequest.WithdrawalRate,
+			guardrailThreshold:    guardrailThreshold,
+			guardrailAdjustment:   guardrailAdjustment,
+			endYear:               forecastRequest.EndYear,
+		}
+		break
+	case models.VariablePercentage:
+		withdrawalStrategy = VariablePercentageStrategy{
+			expectedReturnRate: getExpectedRealReturnRate(forecastRequest),
+			endYear:            forecastRequest.EndYear,
+		}
+		break
+	case models.PercentOfPortfolio:
+		withdrawalStrategy = PercentOfPortfolioStrategy{
+			withdrawalRate:    forecastRequest.WithdrawalRate,
+			withdrawalFloor:   forecastRequest.WithdrawalFloor,
+			withdrawalCeiling: forecastRequest.WithdrawalCeiling,
+		}
+		break
+	case models.RmdStyle:
+		withdrawalStrategy = RmdStyleStrategy{forecastRequest: forecastRequest}
+		break
+	default:
+		withdrawalStrategy = FixedWithdrawalStrategy{}
+		break
+	}
+	return withdrawalStrategy
+}
@@ -0,0 +1,172 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/guilam34/financial_planner/models"
+)
+
+type WithdrawalStrategyTestCase struct {
+	CaseName        string
+	ForecastRequest models.ForecastPortfolioRequest
+	// Portfolio value at the start of each year beginning with year 1
+	PortfolioValues []float64
+	Spending        []float64
+}
+
+var withdrawalStrategyCases = []WithdrawalStrategyTestCase{
+	{
+		CaseName:        "Fixed",
+		ForecastRequest: models.ForecastPortfolioRequest{WithdrawalRate: 0.04},
+		PortfolioValues: []float64{1_000_000},
+		Spending:        []float64{0},
+	},
+	{
+		CaseName:        "ConstantDollar",
+		ForecastRequest: models.ForecastPortfolioRequest{WithdrawalStrategy: models.ConstantDollar, WithdrawalRate: 0.04},
+		PortfolioValues: []float64{1_000_000, 500_000},
+		Spending:        []float64{40_000, 40_000},
+	},
+	{
+		CaseName: "GuytonKlinger",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:            30,
+			WithdrawalStrategy: models.GuytonKlinger,
+			WithdrawalRate:     0.05,
+		},
+		// Cut, raise, hold steady and then skip the cut with 15 years left
+		PortfolioValues: []float64{1_000_000, 800_000, 1_500_000, 1_000_000, 1_000_000, 1_000_000, 1_000_000, 1_000_000,
+			1_000_000, 1_000_000, 1_000_000, 1_000_000, 1_000_000, 1_000_000, 1_000_000, 500_000},
+		Spending: []float64{50_000, 45_000, 49_500, 49_500, 49_500, 49_500, 49_500, 49_500,
+			49_500, 49_500, 49_500, 49_500, 49_500, 49_500, 49_500, 49_500},
+	},
+	{
+		CaseName: "VariablePercentageWithoutReturns",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             10,
+			PortfolioAllocation: singleAssetAllocation,
+			WithdrawalStrategy:  models.VariablePercentage,
+		},
+		PortfolioValues: []float64{100_000, 90_000},
+		Spending:        []float64{10_000, 10_000},
+	},
+	{
+		CaseName: "VariablePercentageWithReturns",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			EndYear:             1,
+			PortfolioAllocation: models.PortfolioAllocation{models.Equities: {ReturnRate: 0.07, Allocation: 1.0}},
+			AnnualInflationRate: 0.02,
+			WithdrawalStrategy:  models.VariablePercentage,
+		},
+		PortfolioValues: []float64{100_000},
+		Spending:        []float64{105_000},
+	},
+	{
+		CaseName: "PercentOfPortfolio",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			WithdrawalStrategy: models.PercentOfPortfolio,
+			WithdrawalRate:     0.04,
+			WithdrawalFloor:    models.Dollars(30_000),
+			WithdrawalCeiling:  models.Dollars(50_000),
+		},
+		PortfolioValues: []float64{500_000, 1_000_000, 2_000_000},
+		Spending:        []float64{30_000, 40_000, 50_000},
+	},
+	{
+		CaseName: "RmdStyle",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			WithdrawalStrategy: models.RmdStyle,
+			CurrentYear:        2024,
+			OwnerBirthYear:     1962,
+		},
+		// Ages 63 and 64 extend the table below its first age of 72
+		PortfolioValues: []float64{364_000, 354_000},
+		Spending:        []float64{10_000, 10_000},
+	},
+}
+
+func TestWithdrawalStrategies(t *testing.T) {
+	for _, test := range withdrawalStrategyCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			withdrawalStrategy := newWithdrawalStrategy(test.ForecastRequest)
+			for i, portfolioValue := range test.PortfolioValues {
+				actual := withdrawalStrategy.NextYear(i+1, models.Dollars(portfolioValue))
+				if actual != models.Dollars(test.Spending[i]) {
+					t.Errorf("expected %v in year %d but got %v", test.Spending[i], i+1, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastWithWithdrawalStrategy(t *testing.T) {
+	response, err := ForecastFuturePortfolioValueByYear(models.ForecastPortfolioRequest{
+		EndYear:             2,
+		PortfolioAllocation: singleAssetAllocation,
+		InitPortfolio:       models.Portfolio{models.Equities: models.Dollars(100_000)},
+		AnnualPortfolioBalanceChanges: []models.AnnualPortfolioBalanceChange{
+			{Amount: models.Dollars(-1_000), StartYear: 2, EndYear: 2},
+		},
+		WithdrawalStrategy: models.ConstantDollar,
+		WithdrawalRate:     0.04,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expectedSpending := []float64{0, 4_000, 5_000}
+	for year, expected := range expectedSpending {
+		if response.Spending[year] != models.Dollars(expected) {
+			t.Errorf("expected %v but got %v", expectedSpending, response.Spending)
+		}
+	}
+	if response.Portfolios[2][models.Equities] != models.Dollars(91_000) {
+		t.Errorf("expected 91000 but got %v", response.Portfolios[2])
+	}
+}
+
+var withdrawalStrategyErrorCases = []PortfolioSimulatorTestCase{
+	{
+		CaseName: "WithdrawalRateOutOfRange",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			WithdrawalRate:      1.5,
+		},
+		ErrorMessage: "withdrawal rate must be between 0 and 1",
+	},
+	{
+		CaseName: "FloorAboveCeiling",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			WithdrawalFloor:     models.Dollars(50_000),
+			WithdrawalCeiling:   models.Dollars(40_000),
+		},
+		ErrorMessage: "withdrawal floor must be less than or equal to the ceiling",
+	},
+	{
+		CaseName: "GuardrailOutOfRange",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			GuardrailThreshold:  -0.1,
+		},
+		ErrorMessage: "guardrail threshold and adjustment must be between 0 and 1",
+	},
+	{
+		CaseName: "RmdStyleWithoutBirthYear",
+		ForecastRequest: models.ForecastPortfolioRequest{
+			PortfolioAllocation: singleAssetAllocation,
+			WithdrawalStrategy:  models.RmdStyle,
+		},
+		ErrorMessage: "rmd style withdrawals require the owner birth year",
+	},
+}
+
+func TestWithdrawalStrategyErrorCases(t *testing.T) {
+	for _, test := range withdrawalStrategyErrorCases {
+		t.Run(test.CaseName, func(t *testing.T) {
+			_, err := ForecastFuturePortfolioValueByYear(test.ForecastRequest)
+			if err == nil || err.Error() != test.ErrorMessage {
+				t.Errorf("expected %v but got %v", test.ErrorMessage, err)
+			}
+		})
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"math"
 )
 
-const float64EqualityThreshold = 1
+// Money is compared exactly in cents, so this only absorbs floating point error in rates and weights
+const float64EqualityThreshold = 1e-9
 
 func AlmostEqual(a, b float64) bool {
 	return math.Abs(a-b) <= float64EqualityThreshold